@@ -648,6 +648,24 @@ func createRefreshToken(user *auth.UserInfo, lifetime time.Duration) (token stri
 type Claims struct {
 }
 
+// SetJWTKey sets the key used to sign and verify tokens. Call it before Setup to use a key
+// that's not in ./.jwtkey (e.g. from a secret store, or a fixed key in tests).
+func SetJWTKey(key []byte) {
+	jwtKey = key
+}
+
+// CreateAccessToken mints an access token for user, as it would be issued by the login endpoint.
+// It can be sent as a bearer token or in the access token cookie. lifetime 0 uses the default (30 min).
+func CreateAccessToken(user *auth.UserInfo, lifetime time.Duration) (string, error) {
+	if jwtKey == nil {
+		return "", errors.New("httpauth: no jwt key, call Setup() or SetJWTKey() first")
+	}
+
+	clone := *user
+	token, _, err := createAccessToken(&clone, lifetime)
+	return token, err
+}
+
 func createAccessToken(user *auth.UserInfo, lifetime time.Duration) (token string, expiry time.Time, err error) {
 
 	if lifetime == 0 {
@@ -16,6 +16,11 @@ func (a *AuthRouter) AddIgnoreRoute(path string) {
 	a.auth.ignoreRoutes = append(a.auth.ignoreRoutes, path)
 }
 
+// AddIgnoreRoutePrefix is AddIgnoreRoute for every path that starts with prefix (see Config.PublicRoutePrefixes)
+func (a *AuthRouter) AddIgnoreRoutePrefix(prefix string) {
+	a.auth.ignoreRoutesWithPrefixes = append(a.auth.ignoreRoutesWithPrefixes, prefix)
+}
+
 func (a *AuthRouter) ManuallySetSession(rq *res.Request, user *auth.UserInfo) error {
 	_, err := setupSession(rq, user, a.config)
 	return err
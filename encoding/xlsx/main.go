@@ -0,0 +1,230 @@
+// Package xlsx is a minimal OOXML (Excel) writer for a single sheet of string cells.
+// Numeric values are written as numbers, everything else as inline strings (never formulas).
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxCellLength is the most characters Excel allows in a cell
+const maxCellLength = 32767
+
+type Encoder interface {
+	WriteRow(values []string) error
+
+	// Close writes the rest of the workbook, nothing is usable until it's called
+	Close() error
+}
+
+type encoder struct {
+	zip   *zip.Writer
+	sheet io.Writer
+	row   int
+
+	// boldFirstRow styles the first row as a header
+	boldFirstRow bool
+}
+
+// NewEncoder starts a workbook with a single sheet named sheetName. When header is true,
+// the first row is bold.
+func NewEncoder(wr io.Writer, sheetName string, header bool) (Encoder, error) {
+	z := zip.NewWriter(wr)
+
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", contentTypes},
+		{"_rels/.rels", rootRels},
+		{"xl/workbook.xml", workbook(sheetName)},
+		{"xl/_rels/workbook.xml.rels", workbookRels},
+		{"xl/styles.xml", styles},
+	}
+
+	for _, file := range files {
+		w, err := z.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := io.WriteString(w, file.content); err != nil {
+			return nil, err
+		}
+	}
+
+	// the sheet has to be the last file so rows can be streamed into it
+	sheet, err := z.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := io.WriteString(sheet, xml.Header+`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`); err != nil {
+		return nil, err
+	}
+
+	return &encoder{
+		zip:          z,
+		sheet:        sheet,
+		boldFirstRow: header,
+	}, nil
+}
+
+func (e *encoder) WriteRow(values []string) error {
+	e.row++
+
+	buf := &bytes.Buffer{}
+	buf.WriteString(`<row r="` + strconv.Itoa(e.row) + `">`)
+
+	style := ""
+	if e.boldFirstRow && e.row == 1 {
+		style = ` s="1"`
+	}
+
+	for i, v := range values {
+		ref := ColumnName(i) + strconv.Itoa(e.row)
+
+		if isNumber(v) && style == "" {
+			buf.WriteString(`<c r="` + ref + `"><v>` + v + `</v></c>`)
+			continue
+		}
+
+		buf.WriteString(`<c r="` + ref + `" t="inlineStr"` + style + `><is><t xml:space="preserve">`)
+		buf.WriteString(escape(v))
+		buf.WriteString(`</t></is></c>`)
+	}
+
+	buf.WriteString(`</row>`)
+
+	_, err := e.sheet.Write(buf.Bytes())
+	return err
+}
+
+func (e *encoder) Close() error {
+	if _, err := io.WriteString(e.sheet, `</sheetData></worksheet>`); err != nil {
+		return err
+	}
+
+	return e.zip.Close()
+}
+
+// Encode writes rows as a complete workbook
+func Encode(wr io.Writer, sheetName string, headers []string, rows [][]string) error {
+	enc, err := NewEncoder(wr, sheetName, len(headers) > 0)
+	if err != nil {
+		return err
+	}
+
+	if len(headers) > 0 {
+		if err := enc.WriteRow(headers); err != nil {
+			return err
+		}
+	}
+
+	for _, row := range rows {
+		if err := enc.WriteRow(row); err != nil {
+			return err
+		}
+	}
+
+	return enc.Close()
+}
+
+// ColumnName converts a 0-based column index to the spreadsheet name (0 => A, 26 => AA)
+func ColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+
+	return name
+}
+
+// isNumber accepts plain decimal numbers. Values with leading zeros (zip codes, ids) stay text.
+func isNumber(v string) bool {
+	if v == "" || len(v) > 15 {
+		return false
+	}
+
+	digits := strings.TrimPrefix(v, "-")
+	if len(digits) > 1 && digits[0] == '0' && digits[1] != '.' {
+		return false
+	}
+
+	for _, c := range digits {
+		if (c < '0' || c > '9') && c != '.' {
+			return false
+		}
+	}
+
+	_, err := strconv.ParseFloat(v, 64)
+	return err == nil
+}
+
+// escape xml-escapes v, dropping characters xml can't contain and truncating to maxCellLength
+func escape(v string) string {
+	if utf8.RuneCountInString(v) > maxCellLength {
+		v = string([]rune(v)[:maxCellLength])
+	}
+
+	v = strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' || (r >= 0x20 && r <= 0xD7FF) || (r >= 0xE000 && r <= 0xFFFD) || (r >= 0x10000 && r <= 0x10FFFF) {
+			return r
+		}
+
+		return -1
+	}, v)
+
+	buf := &bytes.Buffer{}
+	_ = xml.EscapeText(buf, []byte(v))
+	return buf.String()
+}
+
+// sheetNameReplacer removes the characters Excel doesn't allow in sheet names
+var sheetNameReplacer = strings.NewReplacer(`\`, "", "/", "", "?", "", "*", "", "[", "", "]", "", ":", "")
+
+func workbook(sheetName string) string {
+	sheetName = strings.TrimSpace(sheetNameReplacer.Replace(sheetName))
+	if sheetName == "" {
+		sheetName = "Sheet1"
+	}
+
+	if utf8.RuneCountInString(sheetName) > 31 {
+		sheetName = string([]rune(sheetName)[:31])
+	}
+
+	return xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="` + escape(sheetName) + `" sheetId="1" r:id="rId1"/></sheets></workbook>`
+}
+
+const contentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`</Types>`
+
+const rootRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const workbookRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`</Relationships>`
+
+// styles has the default cell format (0) and a bold one for headers (1)
+const styles = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+	`</styleSheet>`
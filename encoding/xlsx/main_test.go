@@ -0,0 +1,62 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestColumnName(t *testing.T) {
+	cases := map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"}
+
+	for index, expected := range cases {
+		if got := ColumnName(index); got != expected {
+			t.Errorf("ColumnName(%d) = %s, expected %s", index, got, expected)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := Encode(buf, "Report", []string{"name", "total"}, [][]string{
+		{"<b>&co", "12.50"},
+		{"=1+1", "007"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rd, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sheet := ""
+	for _, file := range rd.File {
+		if file.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+
+		f, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, _ := io.ReadAll(f)
+		sheet = string(b)
+	}
+
+	for _, expected := range []string{
+		`<c r="A2" t="inlineStr"><is><t xml:space="preserve">&lt;b&gt;&amp;co</t></is></c>`,
+		`<c r="B2"><v>12.50</v></c>`,
+		`<c r="A3" t="inlineStr"><is><t xml:space="preserve">=1+1</t></is></c>`,
+		`<c r="B3" t="inlineStr"><is><t xml:space="preserve">007</t></is></c>`,
+		`<c r="A1" t="inlineStr" s="1">`,
+	} {
+		if !strings.Contains(sheet, expected) {
+			t.Errorf("expected sheet to contain %s\n%s", expected, sheet)
+		}
+	}
+}
@@ -37,7 +37,7 @@ func HandleErrors(callback func(input *HandlerInput)) {
 	if !ok {
 		callback(&HandlerInput{
 			Message:           err.Error(),
-			SuggestedHttpCode: 500,
+			SuggestedHttpCode: HttpStatus(err, http.StatusInternalServerError),
 			StackTrace:        string(debug.Stack()),
 			Error:             err,
 			Details:           getDetails(err),
@@ -47,9 +47,15 @@ func HandleErrors(callback func(input *HandlerInput)) {
 		return
 	}
 
+	// er.Check always uses 500, let the underlying error choose a more specific status
+	code := cause.Code
+	if code == http.StatusInternalServerError {
+		code = HttpStatus(cause.Err, code)
+	}
+
 	callback(&HandlerInput{
 		Message:           cause.Error(),
-		SuggestedHttpCode: cause.Code,
+		SuggestedHttpCode: code,
 		StackTrace:        fmt.Sprintf("%+v", err),
 		Error:             err,
 		Details:           getDetails(cause.Err),
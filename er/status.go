@@ -0,0 +1,28 @@
+package er
+
+import (
+	"github.com/pkg/errors"
+)
+
+// ErrorWithHttpStatus lets an error pick the http status it's reported with when it reaches
+// res.Error or the panic handler through er.Check (which otherwise suggests 500). The check walks
+// the error chain, so wrapped errors keep their status.
+type ErrorWithHttpStatus interface {
+	HttpStatus() int
+}
+
+// HttpStatus returns the status chosen by the first ErrorWithHttpStatus in err's chain,
+// or fallback if there isn't one.
+func HttpStatus(err error, fallback int) int {
+	var target ErrorWithHttpStatus
+	if errors.As(err, &target) {
+		return target.HttpStatus()
+	}
+
+	return fallback
+}
+
+// IsClientSafe reports whether err's message may be shown to the consumer verbatim (see ClientSafeErr)
+func IsClientSafe(err error) bool {
+	return isClientSafeMessage(err)
+}
@@ -0,0 +1,38 @@
+package er
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+type conflictErr struct{}
+
+func (c *conflictErr) Error() string   { return "conflict" }
+func (c *conflictErr) HttpStatus() int { return http.StatusConflict }
+
+func TestHttpStatus(t *testing.T) {
+	if got := HttpStatus(fmt.Errorf("wrapped: %w", &conflictErr{}), 500); got != http.StatusConflict {
+		t.Errorf("expected 409 for wrapped error, got %d", got)
+	}
+
+	if got := HttpStatus(fmt.Errorf("plain"), 500); got != 500 {
+		t.Errorf("expected fallback for plain error, got %d", got)
+	}
+}
+
+func TestCheckUsesErrorHttpStatus(t *testing.T) {
+	code := 0
+
+	func() {
+		defer HandleErrors(func(input *HandlerInput) {
+			code = input.SuggestedHttpCode
+		})
+
+		Check(&conflictErr{})
+	}()
+
+	if code != http.StatusConflict {
+		t.Errorf("expected 409, got %d", code)
+	}
+}
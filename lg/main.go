@@ -75,6 +75,17 @@ func TraceKey(ctx context.Context) string {
 	return obj.key
 }
 
+// CurrentKey returns the trace key of ctx ("" if there isn't one). Unlike TraceKey, it doesn't
+// log the trace origin, so it's safe to call on every query.
+func CurrentKey(ctx context.Context) string {
+	obj := get(ctx)
+	if obj == nil {
+		return ""
+	}
+
+	return obj.key
+}
+
 func NewContext(ctx context.Context, opts ...Option) context.Context {
 	send := get(ctx)
 	if send != nil {
@@ -205,6 +216,21 @@ func PrintObj(ctx context.Context, str string, v map[string]any) {
 	}
 }
 
+// Record is PrintObj without the trace-origin line, for entries that already describe
+// where they come from (e.g. access logs)
+func Record(ctx context.Context, str string, v map[string]any) {
+	select {
+	case queue <- &message{
+		when:   time.Now(),
+		sender: get(ctx),
+		caller: getCaller(2),
+		str:    []byte(str),
+		extra:  v,
+	}:
+	case <-ctx.Done():
+	}
+}
+
 func getCaller(callDepth int) string {
 	var ok bool
 	_, file, line, ok := runtime.Caller(callDepth)
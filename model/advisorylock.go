@@ -0,0 +1,180 @@
+package model
+
+import (
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"hash/fnv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrLockNotAcquired is returned by the try-lock variants when another session holds the lock
+var ErrLockNotAcquired = errors.New("gobase/model: advisory lock is held by another session")
+
+type AdvisoryLockOpts struct {
+	// Wait blocks until the lock is available (or ctx is done).
+	// default: false (return ErrLockNotAcquired right away)
+	Wait bool
+
+	// Session holds the lock on a dedicated connection for the duration of fn instead of in ctx's
+	// transaction. Use it for long-running work that commits several transactions.
+	//
+	// default: false (postgres: pg_advisory_xact_lock in ctx's transaction, or a new transaction
+	// around fn if ctx doesn't have one). MySQL locks (GET_LOCK) are always session-scoped.
+	Session bool
+}
+
+// WithAdvisoryLock runs fn while holding the database-wide lock for key, so only one instance
+// runs fn at a time. If another session holds the lock, fn isn't called and ErrLockNotAcquired is returned.
+//
+//	err := model.WithAdvisoryLock(ctx, "nightly-billing", func(ctx context.Context) error {
+//		return runBilling(ctx)
+//	})
+//
+//	if errors.Is(err, model.ErrLockNotAcquired) {
+//		// another instance is already on it
+//	}
+func WithAdvisoryLock(ctx context.Context, key string, fn func(ctx context.Context) error) error {
+	return WithAdvisoryLockOpts(ctx, key, &AdvisoryLockOpts{}, fn)
+}
+
+// WithAdvisoryLockWait is the blocking version of WithAdvisoryLock
+func WithAdvisoryLockWait(ctx context.Context, key string, fn func(ctx context.Context) error) error {
+	return WithAdvisoryLockOpts(ctx, key, &AdvisoryLockOpts{Wait: true}, fn)
+}
+
+func WithAdvisoryLockOpts(ctx context.Context, key string, opts *AdvisoryLockOpts, fn func(ctx context.Context) error) error {
+	if opts.Session || isMySQL(getDb(ctx)) {
+		return withSessionLock(ctx, key, opts.Wait, fn)
+	}
+
+	if !HasTx(ctx) {
+		return WithTx(ctx, func(ctx context.Context, tx *sqlx.Tx) error {
+			return withTxLock(ctx, key, opts.Wait, fn)
+		})
+	}
+
+	return withTxLock(ctx, key, opts.Wait, fn)
+}
+
+// TryAdvisoryLockTx takes a postgres advisory lock for key that's released when ctx's transaction
+// commits or rolls back. It returns false if another session holds the lock.
+func TryAdvisoryLockTx(ctx context.Context, key string) (bool, error) {
+	var acquired bool
+	err := GetContext(ctx, &acquired, `select pg_try_advisory_xact_lock($1)`, advisoryLockID(key))
+	return acquired, err
+}
+
+// AdvisoryLockTx is the blocking version of TryAdvisoryLockTx
+func AdvisoryLockTx(ctx context.Context, key string) error {
+	return ExecContext(ctx, `select pg_advisory_xact_lock($1)`, advisoryLockID(key))
+}
+
+func withTxLock(ctx context.Context, key string, wait bool, fn func(ctx context.Context) error) error {
+	if wait {
+		if err := AdvisoryLockTx(ctx, key); err != nil {
+			return err
+		}
+
+		return fn(ctx)
+	}
+
+	acquired, err := TryAdvisoryLockTx(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	if !acquired {
+		return ErrLockNotAcquired
+	}
+
+	return fn(ctx)
+}
+
+func withSessionLock(ctx context.Context, key string, wait bool, fn func(ctx context.Context) error) error {
+	conn, err := getDb(ctx).Connx(ctx)
+	if err != nil {
+		return classifyError(err)
+	}
+
+	mysql := isMySQL(getDb(ctx))
+
+	var acquired sql.NullBool
+
+	switch {
+	case mysql && wait:
+		err = conn.GetContext(ctx, &acquired, `select get_lock(?, -1)`, mysqlLockName(key))
+	case mysql:
+		err = conn.GetContext(ctx, &acquired, `select get_lock(?, 0)`, mysqlLockName(key))
+	case wait:
+		_, err = conn.ExecContext(ctx, `select pg_advisory_lock($1)`, advisoryLockID(key))
+		acquired = sql.NullBool{Bool: true, Valid: true}
+	default:
+		err = conn.GetContext(ctx, &acquired, `select pg_try_advisory_lock($1)`, advisoryLockID(key))
+	}
+
+	if err != nil {
+		_ = conn.Close()
+		return classifyError(err)
+	}
+
+	if !acquired.Bool {
+		_ = conn.Close()
+		return ErrLockNotAcquired
+	}
+
+	defer releaseSessionLock(conn, key, mysql)
+	return fn(ctx)
+}
+
+func releaseSessionLock(conn *sqlx.Conn, key string, mysql bool) {
+	var err error
+
+	// the caller's context may already be cancelled, the lock has to be released regardless
+	if mysql {
+		_, err = conn.ExecContext(context.Background(), `select release_lock(?)`, mysqlLockName(key))
+	} else {
+		_, err = conn.ExecContext(context.Background(), `select pg_advisory_unlock($1)`, advisoryLockID(key))
+	}
+
+	if err != nil {
+		verboseError(err)
+
+		// closing the session is the only other way to release the lock
+		_ = conn.Raw(func(driverConn interface{}) error {
+			return driver.ErrBadConn
+		})
+	}
+
+	_ = conn.Close()
+}
+
+// advisoryLockID maps key to the bigint used by postgres advisory locks (stable across instances)
+func advisoryLockID(key string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return int64(h.Sum64())
+}
+
+// mysqlLockName keeps lock names within mysql's 64 character limit
+func mysqlLockName(key string) string {
+	if len(key) <= 64 {
+		return key
+	}
+
+	sum := sha1.Sum([]byte(key))
+	return key[:23] + "-" + hex.EncodeToString(sum[:])
+}
+
+func isMySQL(db *sqlx.DB) bool {
+	if db == nil {
+		return defaultDbType == "mysql"
+	}
+
+	return strings.Contains(db.DriverName(), "mysql")
+}
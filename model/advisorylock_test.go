@@ -0,0 +1,25 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAdvisoryLockKeys(t *testing.T) {
+	if advisoryLockID("nightly-billing") != advisoryLockID("nightly-billing") {
+		t.Error("expected lock id to be stable")
+	}
+
+	if advisoryLockID("a") == advisoryLockID("b") {
+		t.Error("expected different keys to have different lock ids")
+	}
+
+	if mysqlLockName("short") != "short" {
+		t.Error("expected short names to be unchanged")
+	}
+
+	long := strings.Repeat("x", 100)
+	if name := mysqlLockName(long); len(name) > 64 || name == mysqlLockName(long+"y") {
+		t.Error("expected long names to be hashed within 64 characters", name)
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+type Options struct {
+	Package string
+
+	// Mapping is the struct name mapping used by the app (e.g. model.SnakeCaseStructNameMapping)
+	Mapping func(structCol string) string
+
+	// AllTags adds a db tag to every field
+	AllTags bool
+
+	// Cents marks integer columns that hold currency.Cents
+	Cents *regexp.Regexp
+}
+
+// initialisms are kept upper-case in field names (matches the special cases in the struct name mappings)
+var initialisms = map[string]bool{
+	"ID":  true,
+	"URL": true,
+}
+
+// FieldName converts a column (snake_case or lowerCamelCase) to an exported Go name
+//
+//	company_id => CompanyID
+//	contactPerson => ContactPerson
+func FieldName(column string) string {
+	var words []string
+	var word []rune
+
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+	}
+
+	runes := []rune(column)
+	for i, c := range runes {
+		switch {
+		case c == '_' || c == '-' || c == ' ':
+			flush()
+			continue
+		case unicode.IsUpper(c) && i > 0 && !unicode.IsUpper(runes[i-1]):
+			flush()
+		case unicode.IsDigit(c) && i > 0 && !unicode.IsDigit(runes[i-1]):
+			flush()
+		}
+
+		word = append(word, c)
+	}
+
+	flush()
+
+	sb := &strings.Builder{}
+	for _, w := range words {
+		upper := strings.ToUpper(w)
+		if initialisms[upper] {
+			sb.WriteString(upper)
+			continue
+		}
+
+		r := []rune(strings.ToLower(w))
+		r[0] = unicode.ToUpper(r[0])
+		sb.WriteString(string(r))
+	}
+
+	name := sb.String()
+	if name == "" || unicode.IsDigit([]rune(name)[0]) {
+		name = "X" + name
+	}
+
+	return name
+}
+
+// GoType returns the field type for col and the import it needs ("" for none)
+func GoType(col *Column, opts *Options) (typ string, pkg string) {
+	isInt := false
+
+	switch col.UdtName {
+	case "int2", "int4":
+		isInt = true
+		typ = pick(col, "int", "nulls.Int")
+	case "int8":
+		isInt = true
+		typ = pick(col, "int64", "nulls.Int64")
+	case "float4":
+		typ = pick(col, "float32", "nulls.Float32")
+	case "float8", "numeric":
+		typ = pick(col, "float64", "nulls.Float64")
+	case "bool":
+		typ = pick(col, "bool", "nulls.Bool")
+	case "text", "varchar", "bpchar", "citext", "uuid", "time", "timetz", "interval":
+		typ = pick(col, "string", "nulls.String")
+	case "timestamp", "timestamptz":
+		typ = pick(col, "time.Time", "nulls.Time")
+	case "date":
+		typ = pick(col, "model.Date", "model.NullDate")
+	case "bytea":
+		typ = pick(col, "[]byte", "model.NullByteArray")
+	case "json", "jsonb":
+		typ = "json.RawMessage"
+	case "point":
+		typ = "model.NullablePoint"
+	default:
+		return "interface{}", ""
+	}
+
+	if isInt && opts.Cents != nil && opts.Cents.MatchString(col.Name) {
+		typ = pick(col, "currency.Cents", "currency.NullCents")
+	}
+
+	return typ, importFor(typ)
+}
+
+func pick(col *Column, notNull string, nullable string) string {
+	if col.Nullable {
+		return nullable
+	}
+
+	return notNull
+}
+
+func importFor(typ string) string {
+	switch strings.SplitN(strings.TrimPrefix(typ, "*"), ".", 2)[0] {
+	case "nulls":
+		return "github.com/gobuffalo/nulls"
+	case "model":
+		return "github.com/ntbosscher/gobase/model"
+	case "currency":
+		return "github.com/ntbosscher/gobase/currency"
+	case "time":
+		return "time"
+	case "json":
+		return "encoding/json"
+	default:
+		return ""
+	}
+}
+
+// Generate renders the gofmt'd source for tables
+func Generate(tables []*Table, opts *Options) ([]byte, error) {
+	imports := map[string]bool{}
+	body := &bytes.Buffer{}
+
+	for _, table := range tables {
+		fmt.Fprintf(body, "\n// %s mirrors the table %s\n", FieldName(table.Name), table.Name)
+		fmt.Fprintf(body, "type %s struct {\n", FieldName(table.Name))
+
+		for _, col := range table.Columns {
+			name := FieldName(col.Name)
+			typ, pkg := GoType(col, opts)
+			if pkg != "" {
+				imports[pkg] = true
+			}
+
+			tag := ""
+			if opts.AllTags || opts.Mapping == nil || opts.Mapping(name) != col.Name {
+				tag = fmt.Sprintf(" `db:%q`", col.Name)
+			}
+
+			comment := ""
+			if typ == "interface{}" {
+				comment = " // unmapped type " + col.UdtName
+			}
+
+			fmt.Fprintf(body, "\t%s %s%s%s\n", name, typ, tag, comment)
+		}
+
+		body.WriteString("}\n")
+	}
+
+	src := &bytes.Buffer{}
+	src.WriteString("// Code generated by modelgen. DO NOT EDIT.\n\n")
+	src.WriteString("package " + opts.Package + "\n")
+
+	if len(imports) > 0 {
+		var list []string
+		for pkg := range imports {
+			list = append(list, pkg)
+		}
+
+		sort.Strings(list)
+
+		src.WriteString("\nimport (\n")
+		for _, pkg := range list {
+			fmt.Fprintf(src, "\t%q\n", pkg)
+		}
+		src.WriteString(")\n")
+	}
+
+	src.Write(body.Bytes())
+
+	return format.Source(src.Bytes())
+}
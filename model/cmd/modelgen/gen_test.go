@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ntbosscher/gobase/env"
+	"github.com/ntbosscher/gobase/model"
+)
+
+func TestFieldName(t *testing.T) {
+	cases := map[string]string{
+		"id":            "ID",
+		"company_id":    "CompanyID",
+		"contactPerson": "ContactPerson",
+		"companyId":     "CompanyID",
+		"image_url":     "ImageURL",
+		"line2":         "Line2",
+		"2fa_secret":    "X2faSecret",
+	}
+
+	for input, expected := range cases {
+		if got := FieldName(input); got != expected {
+			t.Errorf("FieldName(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	tables := []*Table{{
+		Name: "invoice_line",
+		Columns: []*Column{
+			{Name: "id", UdtName: "int4"},
+			{Name: "company_id", UdtName: "int4"},
+			{Name: "amount_cents", UdtName: "int8", Nullable: true},
+			{Name: "due", UdtName: "date"},
+			{Name: "paid_on", UdtName: "date", Nullable: true},
+			{Name: "note", UdtName: "text", Nullable: true},
+			{Name: "location", UdtName: "point", Nullable: true},
+			{Name: "addr2", UdtName: "varchar"},
+		},
+	}}
+
+	src, err := Generate(tables, &Options{
+		Package: "models",
+		Mapping: model.SnakeCaseStructNameMapping,
+		Cents:   regexp.MustCompile(`(?i)cents$`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := string(src)
+
+	for _, expected := range []string{
+		"type InvoiceLine struct",
+		"ID          int\n",
+		"AmountCents currency.NullCents\n",
+		"Due         model.Date\n",
+		"PaidOn      model.NullDate\n",
+		"Note        nulls.String\n",
+		"Location    model.NullablePoint\n",
+		"Addr2       string `db:\"addr2\"`",
+		`"github.com/gobuffalo/nulls"`,
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected output to contain %q\n%s", expected, out)
+		}
+	}
+}
+
+func TestReadSchema(t *testing.T) {
+	connection := env.Optional("CONNECTION_STRING", "")
+	if connection == "" {
+		t.Skip("CONNECTION_STRING isn't set")
+	}
+
+	if err := model.AddConnection(model.DefaultConnectionKey, "postgres", connection); err != nil {
+		t.Fatal(err)
+	}
+
+	// everything is rolled back by cancel
+	ctx, cancel, err := model.BeginTx(context.Background(), "modelgen-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer cancel()
+	tx := model.Tx(ctx)
+
+	for _, stmt := range []string{
+		`create schema modelgen_test`,
+		`create table modelgen_test.invoice (
+			id serial primary key,
+			total_cents int8 not null,
+			due date not null,
+			paid_on date null,
+			note text null
+		)`,
+	} {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tables, err := readSchema(ctx, tx, "modelgen_test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := Generate(tables, &Options{
+		Package: "models",
+		Mapping: model.SnakeCaseStructNameMapping,
+		Cents:   regexp.MustCompile(`(?i)cents$`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := string(src)
+
+	for _, expected := range []string{
+		"type Invoice struct",
+		"ID         int\n",
+		"TotalCents currency.Cents\n",
+		"Due        model.Date\n",
+		"PaidOn     model.NullDate\n",
+		"Note       nulls.String\n",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected output to contain %q\n%s", expected, out)
+		}
+	}
+}
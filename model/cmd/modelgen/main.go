@@ -0,0 +1,78 @@
+// Command modelgen generates Go structs that mirror the tables of a live Postgres schema.
+//
+// It connects with the CONNECTION_STRING environment variable (like the rest of gobase) and writes
+// one struct per table. db tags are only added where the configured struct name mapping
+// (see model.SetStructNameMapping) wouldn't produce the column name from the field name.
+//
+//	go run github.com/ntbosscher/gobase/model/cmd/modelgen -mapping snake -package models -out models/tables.go
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/ntbosscher/gobase/model"
+)
+
+func main() {
+	schema := flag.String("schema", "public", "database schema to read")
+	tables := flag.String("tables", "", "comma separated list of tables (default: all tables in the schema)")
+	mapping := flag.String("mapping", "snake", "struct name mapping used by the app: snake or camel")
+	pkg := flag.String("package", "models", "package name of the generated file")
+	out := flag.String("out", "", "output file (default: stdout)")
+	cents := flag.String("cents", `(?i)cents$`, "integer columns matching this regexp use currency.Cents")
+	allTags := flag.Bool("all-tags", false, "add a db tag to every field, even if the mapping matches")
+	flag.Parse()
+
+	opts := &Options{
+		Package: *pkg,
+		AllTags: *allTags,
+		Cents:   regexp.MustCompile(*cents),
+	}
+
+	switch *mapping {
+	case "snake":
+		opts.Mapping = model.SnakeCaseStructNameMapping
+	case "camel":
+		opts.Mapping = model.LowerCamelCaseStructNameMapping
+	default:
+		log.Fatal("unknown mapping '" + *mapping + "', expected snake or camel")
+	}
+
+	var filter []string
+	if *tables != "" {
+		filter = strings.Split(*tables, ",")
+	}
+
+	var list []*Table
+
+	err := model.WithTx(context.Background(), func(ctx context.Context, tx *sqlx.Tx) error {
+		var err error
+		list, err = readSchema(ctx, tx, *schema, filter)
+		return err
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	src, err := Generate(list, opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *out == "" {
+		_, _ = os.Stdout.Write(src)
+		return
+	}
+
+	if err := os.WriteFile(*out, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+
+	log.Println("wrote", len(list), "tables to", *out)
+}
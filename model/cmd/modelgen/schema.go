@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type Table struct {
+	Name    string
+	Columns []*Column
+}
+
+type Column struct {
+	Name     string `db:"column_name"`
+	DataType string `db:"data_type"`
+	UdtName  string `db:"udt_name"`
+	Nullable bool   `db:"nullable"`
+}
+
+func readSchema(ctx context.Context, tx *sqlx.Tx, schema string, tables []string) ([]*Table, error) {
+	var rows []struct {
+		Table string `db:"table_name"`
+		Column
+	}
+
+	err := tx.SelectContext(ctx, &rows, `select c.table_name, c.column_name, c.data_type, c.udt_name, c.is_nullable = 'YES' as nullable
+		from information_schema.columns c
+		inner join information_schema.tables t on t.table_schema = c.table_schema and t.table_name = c.table_name
+		where c.table_schema = $1
+		and t.table_type = 'BASE TABLE'
+		and (coalesce(cardinality($2::text[]), 0) = 0 or c.table_name = any($2))
+		order by c.table_name, c.ordinal_position`, schema, pq.Array(tables))
+	if err != nil {
+		return nil, err
+	}
+
+	var list []*Table
+	var current *Table
+
+	for _, row := range rows {
+		if current == nil || current.Name != row.Table {
+			current = &Table{Name: row.Table}
+			list = append(list, current)
+		}
+
+		col := row.Column
+		current.Columns = append(current.Columns, &col)
+	}
+
+	return list, nil
+}
@@ -0,0 +1,252 @@
+package model
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ntbosscher/gobase/auth"
+)
+
+func (d Date) MarshalCSV() (string, error) {
+	return time.Time(d).Format(dateFormat), nil
+}
+
+// String formats the date as yyyy-mm-dd
+func (d Date) String() string {
+	return time.Time(d).Format(dateFormat)
+}
+
+// NullDate is a Date that can be null
+type NullDate struct {
+	Date  Date
+	Valid bool
+}
+
+func NewNullDate(d Date) NullDate {
+	return NullDate{Date: d, Valid: true}
+}
+
+func (n *NullDate) Scan(value interface{}) error {
+	if value == nil {
+		*n = NullDate{}
+		return nil
+	}
+
+	if err := n.Date.Scan(value); err != nil {
+		return err
+	}
+
+	n.Valid = true
+	return nil
+}
+
+func (n NullDate) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+
+	return n.Date.Value()
+}
+
+func (n NullDate) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte(`null`), nil
+	}
+
+	return n.Date.MarshalJSON()
+}
+
+func (n *NullDate) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" || string(b) == `""` {
+		*n = NullDate{}
+		return nil
+	}
+
+	if err := n.Date.UnmarshalJSON(b); err != nil {
+		return err
+	}
+
+	n.Valid = true
+	return nil
+}
+
+func (n NullDate) MarshalCSV() (string, error) {
+	if !n.Valid {
+		return "", nil
+	}
+
+	return n.Date.MarshalCSV()
+}
+
+// UserLocation returns the time zone of the authenticated user. auth.UserInfo.TimeZoneOffset is
+// read the same way as javascript's Date.getTimezoneOffset() (minutes behind UTC, e.g. 300 for UTC-5).
+// Unauthenticated contexts use UTC.
+func UserLocation(ctx context.Context) *time.Location {
+	user := auth.Current(ctx)
+	if user == nil || user.TimeZoneOffset == 0 {
+		return time.UTC
+	}
+
+	return time.FixedZone("", -int(user.TimeZoneOffset)*60)
+}
+
+// DateIn returns the calendar date of tm in loc
+func DateIn(tm time.Time, loc *time.Location) Date {
+	y, m, d := tm.In(loc).Date()
+	return Date(time.Date(y, m, d, 0, 0, 0, 0, time.UTC))
+}
+
+// UserDate returns the user's local calendar date for a stored (UTC) timestamp
+//
+//	createdOn := model.UserDate(ctx, invoice.CreatedAt)
+func UserDate(ctx context.Context, tm time.Time) Date {
+	return DateIn(tm, UserLocation(ctx))
+}
+
+// UserToday is the current date for the user
+func UserToday(ctx context.Context) Date {
+	return UserDate(ctx, time.Now())
+}
+
+// UserStartOfDay returns the UTC instant the user's local day d starts. Use it with UserEndOfDay
+// to filter timestamps by a user-local date:
+//
+//	Where("created_at >= ? and created_at < ?", model.UserStartOfDay(ctx, d), model.UserEndOfDay(ctx, d))
+func UserStartOfDay(ctx context.Context, d Date) time.Time {
+	y, m, day := time.Time(d).Date()
+	return time.Date(y, m, day, 0, 0, 0, 0, UserLocation(ctx)).UTC()
+}
+
+// UserEndOfDay returns the UTC instant the user's local day d ends (exclusive, the start of the next day)
+func UserEndOfDay(ctx context.Context, d Date) time.Time {
+	y, m, day := time.Time(d).Date()
+	return time.Date(y, m, day+1, 0, 0, 0, 0, UserLocation(ctx)).UTC()
+}
+
+// TimeOfDay is a wall-clock time without a date (postgres/mysql "time"), stored as the
+// duration since midnight
+type TimeOfDay time.Duration
+
+func NewTimeOfDay(hour int, minute int, second int) TimeOfDay {
+	return TimeOfDay(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute + time.Duration(second)*time.Second)
+}
+
+func (t TimeOfDay) Hour() int {
+	return int(time.Duration(t) / time.Hour)
+}
+
+func (t TimeOfDay) Minute() int {
+	return int(time.Duration(t) % time.Hour / time.Minute)
+}
+
+func (t TimeOfDay) Second() int {
+	return int(time.Duration(t) % time.Minute / time.Second)
+}
+
+// On returns the time t on date d in loc
+func (t TimeOfDay) On(d Date, loc *time.Location) time.Time {
+	y, m, day := time.Time(d).Date()
+	return time.Date(y, m, day, 0, 0, 0, 0, loc).Add(time.Duration(t))
+}
+
+// String formats as hh:mm:ss (with fractional seconds if present)
+func (t TimeOfDay) String() string {
+	s := fmt.Sprintf("%02d:%02d:%02d", t.Hour(), t.Minute(), t.Second())
+
+	if frac := time.Duration(t) % time.Second; frac != 0 {
+		s += strings.TrimRight(fmt.Sprintf(".%09d", frac), "0")
+	}
+
+	return s
+}
+
+// ParseTimeOfDay parses hh:mm, hh:mm:ss or hh:mm:ss.ffffff
+func ParseTimeOfDay(value string) (TimeOfDay, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, errors.New("invalid time of day '" + value + "'")
+	}
+
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil || hour < 0 || hour > 24 {
+		return 0, errors.New("invalid time of day '" + value + "'")
+	}
+
+	minute, err := strconv.Atoi(parts[1])
+	if err != nil || minute < 0 || minute > 59 {
+		return 0, errors.New("invalid time of day '" + value + "'")
+	}
+
+	var seconds float64
+	if len(parts) == 3 {
+		seconds, err = strconv.ParseFloat(parts[2], 64)
+		if err != nil || seconds < 0 || seconds >= 61 {
+			return 0, errors.New("invalid time of day '" + value + "'")
+		}
+	}
+
+	return TimeOfDay(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute + time.Duration(seconds*float64(time.Second)).Round(time.Microsecond)), nil
+}
+
+func (t *TimeOfDay) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return t.scanString(string(v))
+	case string:
+		return t.scanString(v)
+	case time.Time:
+		*t = TimeOfDay(time.Duration(v.Hour())*time.Hour + time.Duration(v.Minute())*time.Minute +
+			time.Duration(v.Second())*time.Second + time.Duration(v.Nanosecond()))
+		return nil
+	default:
+		return fmt.Errorf("invalid Scan(%T) for model.TimeOfDay", v)
+	}
+}
+
+func (t *TimeOfDay) scanString(value string) error {
+	// timetz values include an offset, which TimeOfDay doesn't keep
+	if i := strings.IndexAny(value, "+-"); i > 0 {
+		value = value[:i]
+	}
+
+	parsed, err := ParseTimeOfDay(value)
+	if err != nil {
+		return err
+	}
+
+	*t = parsed
+	return nil
+}
+
+func (t TimeOfDay) Value() (driver.Value, error) {
+	return t.String(), nil
+}
+
+func (t TimeOfDay) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+func (t *TimeOfDay) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	parsed, err := ParseTimeOfDay(s)
+	if err != nil {
+		return err
+	}
+
+	*t = parsed
+	return nil
+}
+
+func (t TimeOfDay) MarshalCSV() (string, error) {
+	return t.String(), nil
+}
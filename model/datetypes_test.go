@@ -0,0 +1,117 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ntbosscher/gobase/auth"
+)
+
+func TestNullDateJSON(t *testing.T) {
+	var d NullDate
+	if err := json.Unmarshal([]byte(`null`), &d); err != nil || d.Valid {
+		t.Fatal("expected null to be invalid", d, err)
+	}
+
+	if err := json.Unmarshal([]byte(`"2024-03-05"`), &d); err != nil || !d.Valid {
+		t.Fatal("expected date to be valid", d, err)
+	}
+
+	js, _ := json.Marshal(d)
+	if string(js) != `"2024-03-05"` {
+		t.Fatal("unexpected json", string(js))
+	}
+}
+
+func TestUserDate(t *testing.T) {
+	// UTC-5
+	ctx := auth.SetUser(context.Background(), &auth.UserInfo{TimeZoneOffset: 300})
+
+	utc := time.Date(2024, 3, 5, 2, 0, 0, 0, time.UTC)
+	if UserDate(ctx, utc).String() != "2024-03-04" {
+		t.Error("expected previous day in UTC-5, got", UserDate(ctx, utc))
+	}
+
+	d := UserDate(ctx, utc)
+	if start := UserStartOfDay(ctx, d); !start.Equal(time.Date(2024, 3, 4, 5, 0, 0, 0, time.UTC)) {
+		t.Error("unexpected start of day", start)
+	}
+
+	if end := UserEndOfDay(ctx, d); !end.Equal(time.Date(2024, 3, 5, 5, 0, 0, 0, time.UTC)) {
+		t.Error("unexpected end of day", end)
+	}
+}
+
+func TestTimeOfDay(t *testing.T) {
+	var tod TimeOfDay
+	if err := tod.Scan([]byte("13:45:30.5")); err != nil {
+		t.Fatal(err)
+	}
+
+	if tod.Hour() != 13 || tod.Minute() != 45 || tod.Second() != 30 || tod.String() != "13:45:30.5" {
+		t.Fatal("unexpected time of day", tod)
+	}
+
+	if err := tod.Scan("08:00:00-05"); err != nil || tod.String() != "08:00:00" {
+		t.Fatal("unexpected timetz result", tod, err)
+	}
+
+	if _, err := ParseTimeOfDay("25:00"); err == nil {
+		t.Error("expected invalid hour to fail")
+	}
+}
+
+func TestDateRange(t *testing.T) {
+	var r DateRange
+	if err := r.Scan([]byte("[2024-01-01,2024-02-01)")); err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Lower.Date.String() != "2024-01-01" || r.Upper.Date.String() != "2024-02-01" {
+		t.Fatal("unexpected range", r)
+	}
+
+	jan31 := Date(time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC))
+	feb1 := Date(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC))
+	if !r.Contains(jan31) || r.Contains(feb1) {
+		t.Error("unexpected Contains result")
+	}
+
+	if err := r.Scan("[2024-01-01,)"); err != nil || r.Upper.Valid {
+		t.Fatal("expected unbounded upper", r, err)
+	}
+
+	if v, _ := r.Value(); v != "[2024-01-01,)" {
+		t.Error("unexpected value", v)
+	}
+
+	if err := r.Scan("empty"); err != nil || !r.Empty {
+		t.Fatal("expected empty range", r, err)
+	}
+}
+
+func TestTimeRange(t *testing.T) {
+	var r TimeRange
+	if err := r.Scan([]byte(`["2024-01-01 05:00:00+00","2024-01-02 05:30:00.5+05:30")`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if !r.LowerInclusive || r.UpperInclusive {
+		t.Error("unexpected bounds", r)
+	}
+
+	if !r.Lower.Equal(time.Date(2024, 1, 1, 5, 0, 0, 0, time.UTC)) || !r.Upper.Equal(time.Date(2024, 1, 2, 0, 0, 0, 5e8, time.UTC)) {
+		t.Fatal("unexpected range", r.Lower, r.Upper)
+	}
+
+	v, _ := r.Value()
+	if v != `["2024-01-01T05:00:00Z","2024-01-02T05:30:00.5+05:30")` {
+		t.Error("unexpected value", v)
+	}
+
+	if err := r.Scan(`(,infinity)`); err != nil || r.Lower != nil || r.Upper != nil {
+		t.Fatal("expected unbounded range", r, err)
+	}
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ntbosscher/gobase/env"
+)
+
+var testDBOnce sync.Once
+var testDBErr error
+
+// requireDB connects the default connection to CONNECTION_STRING (the init connection is skipped in tests).
+// Tests that need postgres are skipped when it isn't set.
+func requireDB(t *testing.T) {
+	t.Helper()
+
+	connection := env.Optional("CONNECTION_STRING", "")
+	if connection == "" {
+		t.Skip("CONNECTION_STRING isn't set")
+	}
+
+	testDBOnce.Do(func() {
+		testDBErr = AddConnection(DefaultConnectionKey, defaultDbType, connection)
+	})
+
+	if testDBErr != nil {
+		t.Fatal(testDBErr)
+	}
+}
@@ -1,6 +1,10 @@
 package model
 
-import "github.com/lib/pq"
+import (
+	"net/http"
+
+	"github.com/lib/pq"
+)
 
 func IsDuplicateKeyError(err error) bool {
 	pErr, ok := err.(*pq.Error)
@@ -10,3 +14,32 @@ func IsDuplicateKeyError(err error) bool {
 
 	return pErr.Code == "23505"
 }
+
+// StaleObjectError is returned when an optimistic-locking update (see modelutil.UpdateStruct and the
+// `model:"version"` struct tag) matches no rows because the record was changed since it was read.
+// Compare with errors.Is(err, model.ErrStaleObject).
+type StaleObjectError struct {
+	Table string
+	ID    int
+}
+
+var ErrStaleObject = &StaleObjectError{}
+
+func (e *StaleObjectError) Error() string {
+	return "This record was changed by someone else. Reload and try again."
+}
+
+func (e *StaleObjectError) Is(target error) bool {
+	_, ok := target.(*StaleObjectError)
+	return ok
+}
+
+// HttpStatus maps stale-object errors to 409 Conflict (see er.ErrorWithHttpStatus)
+func (e *StaleObjectError) HttpStatus() int {
+	return http.StatusConflict
+}
+
+// IsClientSafeErr allows the message to be shown to the user (see er.ClientSafeErr)
+func (e *StaleObjectError) IsClientSafeErr() bool {
+	return true
+}
@@ -0,0 +1,547 @@
+package model
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// SRIDWGS84 is the spatial reference id for longitude/latitude (GPS) coordinates
+const SRIDWGS84 = 4326
+
+// Coord is a single position, [x, y] (for SRIDWGS84 that's [longitude, latitude], the GeoJSON order)
+type Coord [2]float64
+
+// GeoPoint is a PostGIS geometry(Point). It scans (E)WKB and marshals to GeoJSON.
+type GeoPoint struct {
+	Coord Coord
+	SRID  int
+	Valid bool
+}
+
+// NewGeoPoint creates a WGS84 point
+func NewGeoPoint(lng float64, lat float64) GeoPoint {
+	return GeoPoint{Coord: Coord{lng, lat}, SRID: SRIDWGS84, Valid: true}
+}
+
+// LineString is a PostGIS geometry(LineString)
+type LineString struct {
+	Coords []Coord
+	SRID   int
+	Valid  bool
+}
+
+// Polygon is a PostGIS geometry(Polygon). The first ring is the exterior, the others are holes.
+type Polygon struct {
+	Rings [][]Coord
+	SRID  int
+	Valid bool
+}
+
+const (
+	wkbPoint      = 1
+	wkbLineString = 2
+	wkbPolygon    = 3
+
+	ewkbZ    = 0x80000000
+	ewkbM    = 0x40000000
+	ewkbSRID = 0x20000000
+)
+
+func (p *GeoPoint) Scan(value interface{}) error {
+	*p = GeoPoint{}
+
+	g, err := scanGeometry(value, wkbPoint)
+	if err != nil || g == nil {
+		return err
+	}
+
+	if len(g.rings) == 1 && len(g.rings[0]) == 1 {
+		p.Coord = g.rings[0][0]
+		p.Valid = true
+	}
+
+	p.SRID = g.srid
+	return nil
+}
+
+func (p GeoPoint) Value() (driver.Value, error) {
+	if !p.Valid {
+		return nil, nil
+	}
+
+	return encodeEWKB(wkbPoint, p.SRID, [][]Coord{{p.Coord}}), nil
+}
+
+func (p GeoPoint) MarshalJSON() ([]byte, error) {
+	if !p.Valid {
+		return []byte(`null`), nil
+	}
+
+	return json.Marshal(geoJSON{Type: "Point", Coordinates: p.Coord})
+}
+
+func (p *GeoPoint) UnmarshalJSON(b []byte) error {
+	*p = GeoPoint{}
+
+	if string(b) == "null" {
+		return nil
+	}
+
+	var coord Coord
+	if err := unmarshalGeoJSON(b, "Point", &coord); err != nil {
+		return err
+	}
+
+	*p = GeoPoint{Coord: coord, SRID: SRIDWGS84, Valid: true}
+	return nil
+}
+
+func (l *LineString) Scan(value interface{}) error {
+	*l = LineString{}
+
+	g, err := scanGeometry(value, wkbLineString)
+	if err != nil || g == nil {
+		return err
+	}
+
+	l.Coords = g.rings[0]
+	l.SRID = g.srid
+	l.Valid = true
+	return nil
+}
+
+func (l LineString) Value() (driver.Value, error) {
+	if !l.Valid {
+		return nil, nil
+	}
+
+	return encodeEWKB(wkbLineString, l.SRID, [][]Coord{l.Coords}), nil
+}
+
+func (l LineString) MarshalJSON() ([]byte, error) {
+	if !l.Valid {
+		return []byte(`null`), nil
+	}
+
+	return json.Marshal(geoJSON{Type: "LineString", Coordinates: nonNilCoords(l.Coords)})
+}
+
+func (l *LineString) UnmarshalJSON(b []byte) error {
+	*l = LineString{}
+
+	if string(b) == "null" {
+		return nil
+	}
+
+	var coords []Coord
+	if err := unmarshalGeoJSON(b, "LineString", &coords); err != nil {
+		return err
+	}
+
+	*l = LineString{Coords: coords, SRID: SRIDWGS84, Valid: true}
+	return nil
+}
+
+func (p *Polygon) Scan(value interface{}) error {
+	*p = Polygon{}
+
+	g, err := scanGeometry(value, wkbPolygon)
+	if err != nil || g == nil {
+		return err
+	}
+
+	p.Rings = g.rings
+	p.SRID = g.srid
+	p.Valid = true
+	return nil
+}
+
+func (p Polygon) Value() (driver.Value, error) {
+	if !p.Valid {
+		return nil, nil
+	}
+
+	return encodeEWKB(wkbPolygon, p.SRID, p.Rings), nil
+}
+
+func (p Polygon) MarshalJSON() ([]byte, error) {
+	if !p.Valid {
+		return []byte(`null`), nil
+	}
+
+	rings := make([][]Coord, len(p.Rings))
+	for i, ring := range p.Rings {
+		rings[i] = nonNilCoords(ring)
+	}
+
+	return json.Marshal(geoJSON{Type: "Polygon", Coordinates: rings})
+}
+
+func (p *Polygon) UnmarshalJSON(b []byte) error {
+	*p = Polygon{}
+
+	if string(b) == "null" {
+		return nil
+	}
+
+	var rings [][]Coord
+	if err := unmarshalGeoJSON(b, "Polygon", &rings); err != nil {
+		return err
+	}
+
+	*p = Polygon{Rings: rings, SRID: SRIDWGS84, Valid: true}
+	return nil
+}
+
+type geoJSON struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+func unmarshalGeoJSON(b []byte, expectedType string, coordinates interface{}) error {
+	v := struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	}{}
+
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	if v.Type != expectedType {
+		return errors.New("expected geojson type '" + expectedType + "', got '" + v.Type + "'")
+	}
+
+	return json.Unmarshal(v.Coordinates, coordinates)
+}
+
+func nonNilCoords(coords []Coord) []Coord {
+	if coords == nil {
+		return []Coord{}
+	}
+
+	return coords
+}
+
+type geometry struct {
+	srid  int
+	rings [][]Coord
+}
+
+// scanGeometry decodes the value returned by the driver: hex EWKB (postgis over the text protocol),
+// binary (E)WKB, or mysql's internal format (4 byte srid + WKB)
+func scanGeometry(value interface{}, expectedType uint32) (*geometry, error) {
+	var data []byte
+
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return nil, fmt.Errorf("invalid Scan(%T) for geometry", v)
+	}
+
+	if len(data) == 0 {
+		return nil, errors.New("empty geometry")
+	}
+
+	// mysql is checked first: with srid 0 (mysql's default) the value starts with 00 like big endian wkb
+	if isMySQLGeometry(data) {
+		if g, err := readWKB(data[4:], expectedType); err == nil {
+			if g.srid == 0 {
+				g.srid = int(binary.LittleEndian.Uint32(data))
+			}
+
+			return g, nil
+		}
+	}
+
+	switch {
+	case data[0] == 0 || data[0] == 1:
+		// binary wkb
+	case isHex(data):
+		decoded := make([]byte, hex.DecodedLen(len(data)))
+		if _, err := hex.Decode(decoded, data); err != nil {
+			return nil, err
+		}
+
+		data = decoded
+	default:
+		return nil, errors.New("unrecognized geometry encoding")
+	}
+
+	return readWKB(data, expectedType)
+}
+
+// isMySQLGeometry checks for mysql's layout: a 4 byte srid, then wkb with a known geometry type
+func isMySQLGeometry(data []byte) bool {
+	if len(data) < 9 || data[4] > 1 {
+		return false
+	}
+
+	var order binary.ByteOrder = binary.BigEndian
+	if data[4] == 1 {
+		order = binary.LittleEndian
+	}
+
+	return isWKBType(order.Uint32(data[5:]))
+}
+
+// isWKBType accepts the 2d, ISO z/m and EWKB variants of the types from Point (1) to GeometryCollection (7)
+func isWKBType(typ uint32) bool {
+	typ &^= ewkbZ | ewkbM | ewkbSRID
+	return typ/1000 <= 3 && typ%1000 >= 1 && typ%1000 <= 7
+}
+
+// readWKB decodes a single geometry that must fill data
+func readWKB(data []byte, expectedType uint32) (*geometry, error) {
+	rd := &wkbReader{data: data}
+	g, err := rd.read(expectedType)
+	if err != nil {
+		return nil, err
+	}
+
+	if rd.pos != len(data) {
+		return nil, errors.New("unexpected data after geometry")
+	}
+
+	return g, nil
+}
+
+func isHex(data []byte) bool {
+	if len(data)%2 != 0 {
+		return false
+	}
+
+	for _, c := range data {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+
+	return true
+}
+
+type wkbReader struct {
+	data  []byte
+	pos   int
+	order binary.ByteOrder
+}
+
+var errShortGeometry = errors.New("geometry data is too short")
+
+func (r *wkbReader) uint32() (uint32, error) {
+	if r.pos+4 > len(r.data) {
+		return 0, errShortGeometry
+	}
+
+	v := r.order.Uint32(r.data[r.pos:])
+	r.pos += 4
+	return v, nil
+}
+
+func (r *wkbReader) float64() (float64, error) {
+	if r.pos+8 > len(r.data) {
+		return 0, errShortGeometry
+	}
+
+	v := math.Float64frombits(r.order.Uint64(r.data[r.pos:]))
+	r.pos += 8
+	return v, nil
+}
+
+func (r *wkbReader) read(expectedType uint32) (*geometry, error) {
+	if r.pos >= len(r.data) {
+		return nil, errShortGeometry
+	}
+
+	if r.data[r.pos] == 0 {
+		r.order = binary.BigEndian
+	} else {
+		r.order = binary.LittleEndian
+	}
+
+	r.pos++
+
+	typ, err := r.uint32()
+	if err != nil {
+		return nil, err
+	}
+
+	if !isWKBType(typ) {
+		return nil, fmt.Errorf("unrecognized geometry type %d", typ)
+	}
+
+	g := &geometry{}
+
+	dims := 2
+	if typ&ewkbZ != 0 {
+		dims++
+	}
+
+	if typ&ewkbM != 0 {
+		dims++
+	}
+
+	if typ&ewkbSRID != 0 {
+		srid, err := r.uint32()
+		if err != nil {
+			return nil, err
+		}
+
+		g.srid = int(srid)
+	}
+
+	typ &^= ewkbZ | ewkbM | ewkbSRID
+
+	// ISO wkb encodes z/m in the type number (1001 = Point Z, 2001 = Point M, 3001 = Point ZM)
+	switch typ / 1000 {
+	case 1, 2:
+		dims++
+	case 3:
+		dims += 2
+	}
+
+	typ %= 1000
+
+	if typ != expectedType {
+		return nil, fmt.Errorf("expected geometry type %d, got %d", expectedType, typ)
+	}
+
+	switch typ {
+	case wkbPoint:
+		coord, err := r.coord(dims)
+		if err != nil {
+			return nil, err
+		}
+
+		// postgis encodes an empty point as NaN coordinates
+		if math.IsNaN(coord[0]) && math.IsNaN(coord[1]) {
+			g.rings = [][]Coord{{}}
+		} else {
+			g.rings = [][]Coord{{coord}}
+		}
+	case wkbLineString:
+		coords, err := r.coords(dims)
+		if err != nil {
+			return nil, err
+		}
+
+		g.rings = [][]Coord{coords}
+	case wkbPolygon:
+		n, err := r.uint32()
+		if err != nil {
+			return nil, err
+		}
+
+		for i := uint32(0); i < n; i++ {
+			coords, err := r.coords(dims)
+			if err != nil {
+				return nil, err
+			}
+
+			g.rings = append(g.rings, coords)
+		}
+	}
+
+	return g, nil
+}
+
+func (r *wkbReader) coord(dims int) (Coord, error) {
+	var c Coord
+
+	for i := 0; i < dims; i++ {
+		v, err := r.float64()
+		if err != nil {
+			return c, err
+		}
+
+		// z and m aren't kept
+		if i < 2 {
+			c[i] = v
+		}
+	}
+
+	return c, nil
+}
+
+func (r *wkbReader) coords(dims int) ([]Coord, error) {
+	n, err := r.uint32()
+	if err != nil {
+		return nil, err
+	}
+
+	if int(n) > (len(r.data)-r.pos)/(8*dims) {
+		return nil, errShortGeometry
+	}
+
+	coords := make([]Coord, 0, n)
+	for i := uint32(0); i < n; i++ {
+		c, err := r.coord(dims)
+		if err != nil {
+			return nil, err
+		}
+
+		coords = append(coords, c)
+	}
+
+	return coords, nil
+}
+
+// encodeEWKB returns little-endian hex EWKB, which postgis accepts as geometry input
+func encodeEWKB(typ uint32, srid int, rings [][]Coord) string {
+	buf := &bytes.Buffer{}
+	buf.WriteByte(1)
+
+	if srid != 0 {
+		typ |= ewkbSRID
+	}
+
+	_ = binary.Write(buf, binary.LittleEndian, typ)
+
+	if srid != 0 {
+		_ = binary.Write(buf, binary.LittleEndian, uint32(srid))
+	}
+
+	writeCoords := func(coords []Coord) {
+		_ = binary.Write(buf, binary.LittleEndian, uint32(len(coords)))
+		for _, c := range coords {
+			_ = binary.Write(buf, binary.LittleEndian, c[0])
+			_ = binary.Write(buf, binary.LittleEndian, c[1])
+		}
+	}
+
+	switch typ &^ ewkbSRID {
+	case wkbPoint:
+		c := rings[0][0]
+		_ = binary.Write(buf, binary.LittleEndian, c[0])
+		_ = binary.Write(buf, binary.LittleEndian, c[1])
+	case wkbLineString:
+		writeCoords(rings[0])
+	case wkbPolygon:
+		_ = binary.Write(buf, binary.LittleEndian, uint32(len(rings)))
+		for _, ring := range rings {
+			writeCoords(ring)
+		}
+	}
+
+	return hex.EncodeToString(buf.Bytes())
+}
+
+// BoundingBox is a rectangle in the given SRID (for SRIDWGS84: longitudes for X, latitudes for Y)
+type BoundingBox struct {
+	MinX float64
+	MinY float64
+	MaxX float64
+	MaxY float64
+	SRID int
+}
@@ -0,0 +1,138 @@
+package model
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+)
+
+func TestGeoPointScanEWKB(t *testing.T) {
+	// select 'SRID=4326;POINT(1 2)'::geometry
+	var p GeoPoint
+	if err := p.Scan([]byte("0101000020E6100000000000000000F03F0000000000000040")); err != nil {
+		t.Fatal(err)
+	}
+
+	if !p.Valid || p.SRID != 4326 || p.Coord != (Coord{1, 2}) {
+		t.Fatal("unexpected point", p)
+	}
+
+	// big endian, no srid
+	raw, _ := hex.DecodeString("00000000013FF00000000000004000000000000000")
+	if err := p.Scan(raw); err != nil {
+		t.Fatal(err)
+	}
+
+	if !p.Valid || p.SRID != 0 || p.Coord != (Coord{1, 2}) {
+		t.Fatal("unexpected point", p)
+	}
+
+	// mysql: srid prefix + wkb
+	mysql, _ := hex.DecodeString("E61000000101000000000000000000F03F0000000000000040")
+	if err := p.Scan(mysql); err != nil {
+		t.Fatal(err)
+	}
+
+	if !p.Valid || p.SRID != 4326 || p.Coord != (Coord{1, 2}) {
+		t.Fatal("unexpected point", p)
+	}
+
+	// mysql with srid 0 (the default), which starts with 00 like big endian wkb
+	mysql, _ = hex.DecodeString("000000000101000000000000000000F03F0000000000000040")
+	if err := p.Scan(mysql); err != nil {
+		t.Fatal(err)
+	}
+
+	if !p.Valid || p.SRID != 0 || p.Coord != (Coord{1, 2}) {
+		t.Fatal("unexpected point", p)
+	}
+
+	// big endian point whose x could be mistaken for a mysql srid 0 header
+	raw, _ = hex.DecodeString("000000000100000000000000004000000000000000")
+	if err := p.Scan(raw); err != nil {
+		t.Fatal(err)
+	}
+
+	if p.SRID != 0 || p.Coord != (Coord{0, 2}) {
+		t.Fatal("unexpected point", p)
+	}
+
+	// ISO Point Z
+	if err := p.Scan("01E9030000000000000000F03F00000000000000400000000000000840"); err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Coord != (Coord{1, 2}) {
+		t.Fatal("unexpected point", p)
+	}
+
+	if err := p.Scan(nil); err != nil || p.Valid {
+		t.Fatal("expected null point", p, err)
+	}
+
+	if err := p.Scan("0101000020E6100000000000000000F03F"); err == nil {
+		t.Fatal("expected error for truncated point")
+	}
+
+	if err := p.Scan([]byte{0, 0, 0, 0, 0x99, 0, 0, 0, 0}); err == nil {
+		t.Fatal("expected error for an unrecognized geometry type")
+	}
+
+	if err := p.Scan("0101000000000000000000F03F000000000000004000"); err == nil {
+		t.Fatal("expected error for trailing data")
+	}
+
+	if err := p.Scan("0102000000"); err == nil {
+		t.Fatal("expected error for the wrong geometry type")
+	}
+}
+
+func TestPolygonRoundTrip(t *testing.T) {
+	in := Polygon{
+		Rings: [][]Coord{
+			{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}},
+			{{2, 2}, {3, 2}, {3, 3}, {2, 2}},
+		},
+		SRID:  SRIDWGS84,
+		Valid: true,
+	}
+
+	value, err := in.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out Polygon
+	if err := out.Scan(value); err != nil {
+		t.Fatal(err)
+	}
+
+	if out.SRID != in.SRID || len(out.Rings) != 2 || len(out.Rings[1]) != 4 || out.Rings[0][2] != (Coord{10, 10}) {
+		t.Fatal("unexpected polygon", out)
+	}
+}
+
+func TestGeometryGeoJSON(t *testing.T) {
+	js, _ := json.Marshal(NewGeoPoint(-79.38, 43.65))
+	if string(js) != `{"type":"Point","coordinates":[-79.38,43.65]}` {
+		t.Fatal("unexpected json", string(js))
+	}
+
+	var l LineString
+	if err := json.Unmarshal([]byte(`{"type":"LineString","coordinates":[[1,2],[3,4]]}`), &l); err != nil {
+		t.Fatal(err)
+	}
+
+	if !l.Valid || l.SRID != SRIDWGS84 || len(l.Coords) != 2 || l.Coords[1] != (Coord{3, 4}) {
+		t.Fatal("unexpected line", l)
+	}
+
+	if err := json.Unmarshal([]byte(`{"type":"Point","coordinates":[1,2]}`), &l); err == nil {
+		t.Fatal("expected type mismatch error")
+	}
+
+	js, _ = json.Marshal(Polygon{})
+	if string(js) != `null` {
+		t.Fatal("unexpected json", string(js))
+	}
+}
@@ -0,0 +1,227 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ntbosscher/gobase/er"
+)
+
+// HealthCheckTimeout limits how long each connection ping (and extra check) can take in HealthCheck
+var HealthCheckTimeout = 2 * time.Second
+
+// Stats returns the connection pool stats for each connection added with AddConnection
+// (the default connection is under DefaultConnectionKey)
+func Stats() map[string]sql.DBStats {
+	muAll.RLock()
+	defer muAll.RUnlock()
+
+	out := map[string]sql.DBStats{}
+	for key, db := range otherDbs {
+		out[key] = db.Stats()
+	}
+
+	return out
+}
+
+// PoolStatsFunc reports the stats of a connection pool that isn't managed by this package (e.g. pqshared.Pool).
+// The result should be json-encodable.
+type PoolStatsFunc func() interface{}
+
+var muPoolStats sync.RWMutex
+var poolStats = map[string]PoolStatsFunc{}
+
+// AddPoolStats includes an extra connection pool in AllPoolStats. pqshared adds its pool as "pqshared".
+func AddPoolStats(name string, stats PoolStatsFunc) {
+	muPoolStats.Lock()
+	defer muPoolStats.Unlock()
+
+	poolStats[name] = stats
+}
+
+// AllPoolStats returns the stats of every connection pool: the connections added with AddConnection
+// as "db:<key>" (see PoolStats) and the pools added with AddPoolStats under their name.
+func AllPoolStats() map[string]interface{} {
+	out := map[string]interface{}{}
+
+	for key, stats := range Stats() {
+		out["db:"+key] = newPoolStats(stats)
+	}
+
+	muPoolStats.RLock()
+	defer muPoolStats.RUnlock()
+
+	for name, stats := range poolStats {
+		out[name] = stats()
+	}
+
+	return out
+}
+
+// HealthCheckFunc checks a dependency. stats is included in the health report as-is and should be
+// json-encodable (it may be nil).
+type HealthCheckFunc func(ctx context.Context) (stats interface{}, err error)
+
+var muHealthChecks sync.RWMutex
+var healthChecks = map[string]HealthCheckFunc{}
+
+// AddHealthCheck includes an extra dependency in HealthCheck, e.g.
+//
+//	model.AddHealthCheck("pqshared", pqshared.HealthCheck)
+func AddHealthCheck(name string, check HealthCheckFunc) {
+	muHealthChecks.Lock()
+	defer muHealthChecks.Unlock()
+
+	healthChecks[name] = check
+}
+
+type HealthStatus struct {
+	Ok     bool                    `json:"ok"`
+	Checks map[string]*HealthEntry `json:"checks"`
+}
+
+type HealthEntry struct {
+	Ok       bool          `json:"ok"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+	Stats    interface{}   `json:"stats,omitempty"`
+}
+
+// PoolStats is the json-friendly version of sql.DBStats used in health reports
+type PoolStats struct {
+	MaxOpenConnections int           `json:"maxOpenConnections"`
+	OpenConnections    int           `json:"openConnections"`
+	InUse              int           `json:"inUse"`
+	Idle               int           `json:"idle"`
+	WaitCount          int64         `json:"waitCount"`
+	WaitDuration       time.Duration `json:"waitDuration"`
+	MaxIdleClosed      int64         `json:"maxIdleClosed"`
+	MaxLifetimeClosed  int64         `json:"maxLifetimeClosed"`
+}
+
+func newPoolStats(s sql.DBStats) *PoolStats {
+	return &PoolStats{
+		MaxOpenConnections: s.MaxOpenConnections,
+		OpenConnections:    s.OpenConnections,
+		InUse:              s.InUse,
+		Idle:               s.Idle,
+		WaitCount:          s.WaitCount,
+		WaitDuration:       s.WaitDuration,
+		MaxIdleClosed:      s.MaxIdleClosed,
+		MaxLifetimeClosed:  s.MaxLifetimeClosed,
+	}
+}
+
+// HealthCheck pings every registered connection and runs the checks added with AddHealthCheck
+// in parallel, each limited to HealthCheckTimeout. Connections are reported as "db:<key>".
+//
+// Error details are only included when er.ReturnErrorMessageToClient is set, otherwise they're
+// logged to er.ErrorLog so connection details aren't disclosed by a public endpoint.
+func HealthCheck(ctx context.Context) *HealthStatus {
+	checks := map[string]HealthCheckFunc{}
+
+	muAll.RLock()
+	for key, db := range otherDbs {
+		db := db
+		checks["db:"+key] = func(ctx context.Context) (interface{}, error) {
+			err := db.PingContext(ctx)
+			return newPoolStats(db.Stats()), err
+		}
+	}
+	muAll.RUnlock()
+
+	muHealthChecks.RLock()
+	for name, check := range healthChecks {
+		checks[name] = check
+	}
+	muHealthChecks.RUnlock()
+
+	status := &HealthStatus{
+		Ok:     true,
+		Checks: map[string]*HealthEntry{},
+	}
+
+	mu := sync.Mutex{}
+	wait := sync.WaitGroup{}
+
+	for name, check := range checks {
+		wait.Add(1)
+
+		go func(name string, check HealthCheckFunc) {
+			defer wait.Done()
+
+			entry := runHealthCheck(ctx, name, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			status.Checks[name] = entry
+			if !entry.Ok {
+				status.Ok = false
+			}
+		}(name, check)
+	}
+
+	wait.Wait()
+	return status
+}
+
+func runHealthCheck(ctx context.Context, name string, check HealthCheckFunc) (entry *HealthEntry) {
+	ctx, cancel := context.WithTimeout(ctx, HealthCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	entry = &HealthEntry{}
+
+	defer er.HandleErrors(func(input *er.HandlerInput) {
+		entry.Ok = false
+		entry.Duration = time.Since(start)
+		entry.Error = healthError(name, input.Message)
+	})
+
+	stats, err := check(ctx)
+	entry.Duration = time.Since(start)
+	entry.Stats = stats
+	entry.Ok = err == nil
+
+	if err != nil {
+		entry.Error = healthError(name, err.Error())
+	}
+
+	return entry
+}
+
+func healthError(name string, message string) string {
+	er.ErrorLog.Println("gobase/model: health check " + name + " failed: " + message)
+
+	if er.ReturnErrorMessageToClient {
+		return message
+	}
+
+	return "unavailable"
+}
+
+// HealthHandler serves HealthCheck as json for load-balancer readiness checks. It responds
+// 200 when every check passes and 503 otherwise.
+//
+// The handler doesn't need a transaction, add its path to AttachTxHandler's ignore list.
+func HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := HealthCheck(r.Context())
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+
+		if status.Ok {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+
+		_ = json.NewEncoder(w).Encode(status)
+	})
+}
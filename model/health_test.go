@@ -0,0 +1,65 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ntbosscher/gobase/er"
+)
+
+func TestRunHealthCheck(t *testing.T) {
+	entry := runHealthCheck(context.Background(), "ok", func(ctx context.Context) (interface{}, error) {
+		return 1, nil
+	})
+
+	if !entry.Ok || entry.Stats != 1 || entry.Error != "" {
+		t.Fatal("expected ok entry", entry)
+	}
+
+	returnErrorMessage := er.ReturnErrorMessageToClient
+	t.Cleanup(func() {
+		er.ReturnErrorMessageToClient = returnErrorMessage
+	})
+
+	er.ReturnErrorMessageToClient = false
+	entry = runHealthCheck(context.Background(), "failed", func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New("dial tcp 10.0.0.1:5432: connection refused")
+	})
+
+	if entry.Ok || entry.Error != "unavailable" {
+		t.Fatal("expected failure without details", entry)
+	}
+
+	entry = runHealthCheck(context.Background(), "panic", func(ctx context.Context) (interface{}, error) {
+		panic("boom")
+	})
+
+	if entry.Ok || entry.Error != "unavailable" {
+		t.Fatal("expected panic to be reported as failure", entry)
+	}
+}
+
+func TestAllPoolStats(t *testing.T) {
+	AddPoolStats("test-pool", func() interface{} {
+		return 5
+	})
+
+	t.Cleanup(func() {
+		muPoolStats.Lock()
+		defer muPoolStats.Unlock()
+
+		delete(poolStats, "test-pool")
+	})
+
+	stats := AllPoolStats()
+	if stats["test-pool"] != 5 {
+		t.Error("expected the added pool's stats, got", stats)
+	}
+
+	for key := range Stats() {
+		if _, ok := stats["db:"+key].(*PoolStats); !ok {
+			t.Error("expected stats for connection", key)
+		}
+	}
+}
@@ -2,24 +2,70 @@ package model
 
 import (
 	"context"
+	"reflect"
+	"sync"
 	"time"
 )
 
-func preHook(ctx context.Context, method string, query string, args []interface{}) (after func(err error)) {
+func preHook(ctx context.Context, method string, query string, args []interface{}) (after func(err error, rows int64)) {
+	checkTenantScope(ctx, query)
+
 	v := ctx.Value(hookContextKey)
-	if v == nil {
-		return func(err error) {
+	global := getGlobalHooks()
+
+	if v == nil && len(global) == 0 {
+		return func(err error, rows int64) {
 			// noop
 		}
 	}
 
 	start := time.Now()
 
-	return func(err error) {
+	return func(err error, rows int64) {
 		end := time.Now()
 
-		fx := v.(Hook)
-		fx(ctx, method, query, args, err, start, end)
+		if v != nil {
+			fx := v.(Hook)
+			fx(ctx, method, query, args, err, start, end)
+		}
+
+		if len(global) > 0 {
+			runGlobalHooks(ctx, global, &QueryEvent{
+				Method:        method,
+				Query:         query,
+				Args:          args,
+				Error:         err,
+				Start:         start,
+				End:           end,
+				Rows:          rows,
+				ConnectionKey: ConnectionKey(ctx),
+				TxTraceID:     txTraceID(ctx),
+			})
+		}
+	}
+}
+
+// txHook reports transaction operations (Begin, Commit, Rollback) to the global hooks
+func txHook(method string) (after func(ctx context.Context, err error)) {
+	global := getGlobalHooks()
+	if len(global) == 0 {
+		return func(ctx context.Context, err error) {
+			// noop
+		}
+	}
+
+	start := time.Now()
+
+	return func(ctx context.Context, err error) {
+		runGlobalHooks(ctx, global, &QueryEvent{
+			Method:        method,
+			Error:         err,
+			Start:         start,
+			End:           time.Now(),
+			Rows:          -1,
+			ConnectionKey: ConnectionKey(ctx),
+			TxTraceID:     txTraceID(ctx),
+		})
 	}
 }
 
@@ -31,3 +77,98 @@ const hookContextKey hookContextKeyType = "hook-key"
 func SetHook(ctx context.Context, hook Hook) context.Context {
 	return context.WithValue(ctx, hookContextKey, hook)
 }
+
+// QueryEvent describes a finished query or transaction operation
+type QueryEvent struct {
+	// Method is the model function (e.g. "ExecContext", "SelectContext") or the
+	// transaction operation ("Begin", "Commit", "Rollback")
+	Method string
+	Query  string
+	Args   []interface{}
+	Error  error
+	Start  time.Time
+	End    time.Time
+
+	// Rows is the number of rows returned or affected, -1 if unknown
+	Rows int64
+
+	ConnectionKey string
+
+	// TxTraceID is BeginTx2Options.TraceID of the transaction the query ran in
+	TxTraceID string
+}
+
+func (e *QueryEvent) Duration() time.Duration {
+	return e.End.Sub(e.Start)
+}
+
+// GlobalHook is called after every query and transaction operation (see AddGlobalHook)
+type GlobalHook func(ctx context.Context, event *QueryEvent)
+
+type globalHookEntry struct {
+	hook GlobalHook
+}
+
+var muGlobalHooks sync.RWMutex
+var globalHooks []*globalHookEntry
+
+// AddGlobalHook registers hook for every query on every context (unlike SetHook which is per-context).
+// Hooks run synchronously after the query, so they should be fast. Call remove to unregister.
+func AddGlobalHook(hook GlobalHook) (remove func()) {
+	entry := &globalHookEntry{hook: hook}
+
+	muGlobalHooks.Lock()
+	globalHooks = append(append([]*globalHookEntry{}, globalHooks...), entry)
+	muGlobalHooks.Unlock()
+
+	return func() {
+		muGlobalHooks.Lock()
+		defer muGlobalHooks.Unlock()
+
+		list := []*globalHookEntry{}
+		for _, item := range globalHooks {
+			if item != entry {
+				list = append(list, item)
+			}
+		}
+
+		globalHooks = list
+	}
+}
+
+func getGlobalHooks() []*globalHookEntry {
+	muGlobalHooks.RLock()
+	defer muGlobalHooks.RUnlock()
+
+	return globalHooks
+}
+
+func runGlobalHooks(ctx context.Context, hooks []*globalHookEntry, event *QueryEvent) {
+	for _, entry := range hooks {
+		entry.hook(ctx, event)
+	}
+}
+
+func txTraceID(ctx context.Context) string {
+	info, ok := ctx.Value(transactionContextKey).(*txInfo)
+	if !ok {
+		return ""
+	}
+
+	return info.traceId
+}
+
+// rowCount returns the length of a *[]T select destination
+func rowCount(dest interface{}) int64 {
+	value := reflect.ValueOf(dest)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return -1
+	}
+
+	value = value.Elem()
+	if value.Kind() != reflect.Slice {
+		return -1
+	}
+
+	return int64(value.Len())
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestGlobalHook(t *testing.T) {
+	var events []*QueryEvent
+
+	remove := AddGlobalHook(func(ctx context.Context, event *QueryEvent) {
+		events = append(events, event)
+	})
+
+	ctx := UseConnection(context.Background(), "reporting")
+
+	after := preHook(ctx, "SelectContext", "select 1", nil)
+	after(nil, 3)
+
+	after = preHook(ctx, "ExecContext", "delete from x", nil)
+	after(errors.New("failed"), 0)
+
+	remove()
+
+	after = preHook(ctx, "ExecContext", "select 2", nil)
+	after(nil, 1)
+
+	if len(events) != 2 {
+		t.Fatal("expected 2 events, got", len(events))
+	}
+
+	if events[0].Method != "SelectContext" || events[0].Rows != 3 || events[0].ConnectionKey != "reporting" || events[0].Error != nil {
+		t.Fatal("unexpected event", events[0])
+	}
+
+	if events[1].Error == nil || events[1].Query != "delete from x" {
+		t.Fatal("unexpected event", events[1])
+	}
+}
+
+func TestRowCount(t *testing.T) {
+	if n := rowCount(&[]int{1, 2}); n != 2 {
+		t.Fatal("expected 2, got", n)
+	}
+
+	if n := rowCount(&struct{}{}); n != -1 {
+		t.Fatal("expected -1, got", n)
+	}
+}
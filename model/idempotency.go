@@ -0,0 +1,389 @@
+package model
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/ntbosscher/gobase/auth"
+)
+
+// IdempotencyTable stores the responses saved by IdempotencyHandler.
+// Change it before calling IdempotencyHandler if the default name collides with your schema.
+var IdempotencyTable = "model_idempotency_keys"
+
+type IdempotencyOpts struct {
+	// Header holds the client's idempotency key
+	// default: Idempotency-Key
+	Header string
+
+	// TTL is how long a response is replayed for
+	// default: 24h
+	TTL time.Duration
+
+	// Scope namespaces keys so different callers can't replay each other's responses
+	// default: the authenticated user and company (see auth), "" for anonymous requests
+	Scope func(r *http.Request) string
+
+	// MaxKeyLength rejects longer keys with a 400
+	// default: 255
+	MaxKeyLength int
+
+	// MaxResponseBytes is the largest response that's saved, larger responses aren't replayed
+	// default: 1MB
+	MaxResponseBytes int
+
+	// CleanupInterval is how often expired keys are deleted. Negative disables the cleanup.
+	// default: 1h
+	CleanupInterval time.Duration
+
+	// SkipMigrate skips creating IdempotencyTable
+	SkipMigrate bool
+}
+
+// IdempotencyHandler makes POST, PUT, PATCH and DELETE requests with an Idempotency-Key header safe to retry.
+// The first request's response is saved in IdempotencyTable as part of the request's transaction, so it's only
+// kept when the transaction commits (status < 400). Retries with the same key get the saved response back
+// (with an Idempotent-Replayed: true header) without running the handler again.
+//
+// A retry that arrives while the first request is still running gets a 409 Conflict, and reusing a
+// key for a different request (method, path or body) gets a 422. Keys expire after opts.TTL.
+// A response that can't be saved is logged and the request's changes still commit, it just isn't replayed.
+//
+// Handlers that call Commit themselves end the transaction (and the lock that detects concurrent
+// retries) early. Their response is saved in a separate transaction afterwards, so a retry that
+// arrives in between runs the handler again.
+//
+// It must run inside AttachTxHandler:
+//
+//	idempotency, err := model.IdempotencyHandler(nil)
+//	er.Check(err)
+//	router.Use(model.AttachTxHandler(), idempotency)
+func IdempotencyHandler(opts *IdempotencyOpts) (func(next http.Handler) http.Handler, error) {
+	if opts == nil {
+		opts = &IdempotencyOpts{}
+	}
+
+	if opts.Header == "" {
+		opts.Header = "Idempotency-Key"
+	}
+
+	if opts.TTL <= 0 {
+		opts.TTL = 24 * time.Hour
+	}
+
+	if opts.Scope == nil {
+		opts.Scope = defaultIdempotencyScope
+	}
+
+	if opts.MaxKeyLength <= 0 {
+		opts.MaxKeyLength = 255
+	}
+
+	if opts.MaxResponseBytes <= 0 {
+		opts.MaxResponseBytes = 1024 * 1024
+	}
+
+	if opts.CleanupInterval == 0 {
+		opts.CleanupInterval = time.Hour
+	}
+
+	if !opts.SkipMigrate {
+		if err := migrateIdempotency(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.CleanupInterval > 0 {
+		go idempotencyCleaner(opts.CleanupInterval)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return &idempotencyRouter{next: next, opts: opts}
+	}, nil
+}
+
+func defaultIdempotencyScope(r *http.Request) string {
+	ctx := r.Context()
+	if !auth.IsAuthenticated(ctx) {
+		return ""
+	}
+
+	return strconv.Itoa(auth.Company(ctx)) + ":" + strconv.Itoa(auth.User(ctx))
+}
+
+func migrateIdempotency(ctx context.Context) error {
+	return WithTx(ctx, func(ctx context.Context, tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, `create table if not exists `+IdempotencyTable+` (
+			scope text not null,
+			key text not null,
+			fingerprint text not null,
+			status int not null,
+			headers json not null,
+			body bytea not null,
+			created_at timestamp not null,
+			expires_at timestamp not null,
+			primary key (scope, key)
+		)`)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `create index if not exists ix_`+IdempotencyTable+`_expires_at on `+IdempotencyTable+` (expires_at)`)
+		return err
+	})
+}
+
+func idempotencyCleaner(interval time.Duration) {
+	tc := time.NewTicker(interval)
+	defer tc.Stop()
+
+	for range tc.C {
+		if err := CleanupIdempotencyKeys(context.Background()); err != nil {
+			log.Println("gobase/model: idempotency cleanup:", err)
+		}
+	}
+}
+
+// CleanupIdempotencyKeys deletes expired keys. IdempotencyHandler runs it every IdempotencyOpts.CleanupInterval.
+func CleanupIdempotencyKeys(ctx context.Context) error {
+	return WithTx(ctx, func(ctx context.Context, tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, `delete from `+IdempotencyTable+` where expires_at < $1`, time.Now().UTC())
+		return err
+	})
+}
+
+type idempotencyRouter struct {
+	next http.Handler
+	opts *IdempotencyOpts
+}
+
+type idempotencyEntry struct {
+	Fingerprint string    `db:"fingerprint"`
+	Status      int       `db:"status"`
+	Headers     []byte    `db:"headers"`
+	Body        []byte    `db:"body"`
+	ExpiresAt   time.Time `db:"expires_at"`
+}
+
+func (router *idempotencyRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get(router.opts.Header)
+	if key == "" || !isUnsafeMethod(r.Method) {
+		router.next.ServeHTTP(w, r)
+		return
+	}
+
+	if len(key) > router.opts.MaxKeyLength {
+		writeIdempotencyError(w, http.StatusBadRequest, router.opts.Header+" is too long")
+		return
+	}
+
+	ctx := r.Context()
+	if !HasTx(ctx) {
+		log.Println("gobase/model: IdempotencyHandler must run inside AttachTxHandler, ignoring " + router.opts.Header)
+		router.next.ServeHTTP(w, r)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeIdempotencyError(w, http.StatusBadRequest, "failed to read request body")
+		return
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	scope := router.opts.Scope(r)
+	fingerprint := idempotencyFingerprint(r, body)
+
+	// held until the request's transaction ends, so the saved response becomes
+	// visible at the same moment the lock is released
+	acquired, err := TryAdvisoryLockTx(ctx, idempotencyLockKey(scope, key))
+	if err != nil {
+		verboseError(err)
+		writeIdempotencyError(w, http.StatusInternalServerError, "failed to check "+router.opts.Header)
+		return
+	}
+
+	if !acquired {
+		writeIdempotencyError(w, http.StatusConflict, "a request with this "+router.opts.Header+" is already in progress")
+		return
+	}
+
+	entry := &idempotencyEntry{}
+	err = GetContext(ctx, entry, `select fingerprint, status, headers, body, expires_at from `+IdempotencyTable+`
+		where scope = $1 and key = $2 and expires_at > $3`, scope, key, time.Now().UTC())
+
+	switch {
+	case err == nil:
+		if entry.Fingerprint != fingerprint {
+			writeIdempotencyError(w, http.StatusUnprocessableEntity, router.opts.Header+" was already used for a different request")
+			return
+		}
+
+		replayIdempotentResponse(w, entry)
+		return
+	case !errors.Is(err, sql.ErrNoRows):
+		verboseError(err)
+		writeIdempotencyError(w, http.StatusInternalServerError, "failed to check "+router.opts.Header)
+		return
+	}
+
+	recorder := &idempotencyRecorder{ResponseWriter: w, maxBytes: router.opts.MaxResponseBytes}
+	router.next.ServeHTTP(recorder, r)
+
+	if recorder.status == 0 || recorder.status >= 400 || recorder.tooLarge {
+		return
+	}
+
+	if getInfo(ctx).commitCalled {
+		// the handler committed by itself, so the request's transaction (and the lock) is gone.
+		// A retry could have started the handler again since then, see IdempotencyHandler.
+		ctx = context.WithoutCancel(ctx)
+		err = WithTx(ctx, func(ctx context.Context, tx *sqlx.Tx) error {
+			return saveIdempotentResponse(ctx, scope, key, fingerprint, recorder, router.opts.TTL)
+		})
+	} else {
+		err = saveIdempotentResponseSavepoint(ctx, scope, key, fingerprint, recorder, router.opts.TTL)
+	}
+
+	if err != nil {
+		log.Println("gobase/model: failed to save idempotent response:", err)
+	}
+}
+
+func idempotencyLockKey(scope string, key string) string {
+	return "idempotency:" + scope + ":" + key
+}
+
+func isUnsafeMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+func idempotencyFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func saveIdempotentResponse(ctx context.Context, scope string, key string, fingerprint string, recorder *idempotencyRecorder, ttl time.Duration) error {
+	headers, err := json.Marshal(recorder.header)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+
+	// replaces an expired entry that hasn't been cleaned up yet
+	return ExecContext(ctx, `insert into `+IdempotencyTable+` (scope, key, fingerprint, status, headers, body, created_at, expires_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8)
+		on conflict (scope, key) do update set
+			fingerprint = excluded.fingerprint,
+			status = excluded.status,
+			headers = excluded.headers,
+			body = excluded.body,
+			created_at = excluded.created_at,
+			expires_at = excluded.expires_at`,
+		scope, key, fingerprint, recorder.status, headers, recorder.body.Bytes(), now, now.Add(ttl))
+}
+
+// saveIdempotentResponseSavepoint saves the response behind a savepoint. The response has already been
+// written, so a failed save must not abort the request's transaction and roll back the handler's changes.
+func saveIdempotentResponseSavepoint(ctx context.Context, scope string, key string, fingerprint string, recorder *idempotencyRecorder, ttl time.Duration) error {
+	if err := ExecContext(ctx, `savepoint idempotency_save`); err != nil {
+		return err
+	}
+
+	saveErr := saveIdempotentResponse(ctx, scope, key, fingerprint, recorder, ttl)
+	if saveErr == nil {
+		return ExecContext(ctx, `release savepoint idempotency_save`)
+	}
+
+	if err := ExecContext(ctx, `rollback to savepoint idempotency_save`); err != nil {
+		return err
+	}
+
+	return saveErr
+}
+
+func replayIdempotentResponse(w http.ResponseWriter, entry *idempotencyEntry) {
+	header := http.Header{}
+	if err := json.Unmarshal(entry.Headers, &header); err != nil {
+		verboseError(err)
+	}
+
+	for name, values := range header {
+		w.Header()[name] = values
+	}
+
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(entry.Status)
+	w.Write(entry.Body)
+}
+
+func writeIdempotencyError(w http.ResponseWriter, status int, message string) {
+	js, _ := json.Marshal(map[string]string{"error": message})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(js)
+}
+
+// idempotencyRecorder passes the response through while keeping a copy to save
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status   int
+	header   http.Header
+	body     bytes.Buffer
+	maxBytes int
+	tooLarge bool
+}
+
+func (i *idempotencyRecorder) WriteHeader(status int) {
+	if i.status == 0 && status >= 200 {
+		i.status = status
+		i.header = i.ResponseWriter.Header().Clone()
+		i.header.Del("Set-Cookie")
+	}
+
+	i.ResponseWriter.WriteHeader(status)
+}
+
+func (i *idempotencyRecorder) Write(data []byte) (int, error) {
+	if i.status == 0 {
+		i.WriteHeader(http.StatusOK)
+	}
+
+	if !i.tooLarge {
+		if i.body.Len()+len(data) > i.maxBytes {
+			i.tooLarge = true
+			i.body.Reset()
+		} else {
+			i.body.Write(data)
+		}
+	}
+
+	return i.ResponseWriter.Write(data)
+}
+
+// Unwrap exposes the underlying ResponseWriter so that http.ResponseController
+// can reach the real Flusher/Hijacker beneath the recorder.
+func (i *idempotencyRecorder) Unwrap() http.ResponseWriter {
+	return i.ResponseWriter
+}
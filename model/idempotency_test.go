@@ -0,0 +1,243 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/ntbosscher/gobase/er"
+)
+
+func TestIdempotencyFingerprint(t *testing.T) {
+	base := idempotencyFingerprint(httptest.NewRequest("POST", "/api/order?x=1", nil), []byte(`{"id":1}`))
+
+	if base != idempotencyFingerprint(httptest.NewRequest("POST", "/api/order?x=1", nil), []byte(`{"id":1}`)) {
+		t.Error("same request should have the same fingerprint")
+	}
+
+	others := map[string]string{
+		"method": idempotencyFingerprint(httptest.NewRequest("PUT", "/api/order?x=1", nil), []byte(`{"id":1}`)),
+		"path":   idempotencyFingerprint(httptest.NewRequest("POST", "/api/order/2?x=1", nil), []byte(`{"id":1}`)),
+		"query":  idempotencyFingerprint(httptest.NewRequest("POST", "/api/order?x=2", nil), []byte(`{"id":1}`)),
+		"body":   idempotencyFingerprint(httptest.NewRequest("POST", "/api/order?x=1", nil), []byte(`{"id":2}`)),
+	}
+
+	for name, value := range others {
+		if value == base {
+			t.Errorf("different %s should change the fingerprint", name)
+		}
+	}
+}
+
+func TestIdempotencyRecorder(t *testing.T) {
+	w := httptest.NewRecorder()
+	recorder := &idempotencyRecorder{ResponseWriter: w, maxBytes: 10}
+
+	recorder.Header().Set("Content-Type", "application/json")
+	recorder.Header().Set("Set-Cookie", "session=1")
+	recorder.WriteHeader(http.StatusCreated)
+	recorder.Write([]byte(`{"id":1}`))
+
+	if recorder.status != http.StatusCreated || recorder.body.String() != `{"id":1}` || recorder.tooLarge {
+		t.Fatal("incorrect recording", recorder.status, recorder.body.String())
+	}
+
+	if recorder.header.Get("Content-Type") != "application/json" || recorder.header.Get("Set-Cookie") != "" {
+		t.Error("incorrect headers recorded", recorder.header)
+	}
+
+	recorder.Write([]byte(`{"id":2}`))
+	if !recorder.tooLarge || recorder.body.Len() != 0 {
+		t.Error("expected the recording to be dropped when it's over maxBytes")
+	}
+
+	if w.Code != http.StatusCreated || w.Body.String() != `{"id":1}{"id":2}` {
+		t.Error("response should be passed through", w.Code, w.Body.String())
+	}
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	w := httptest.NewRecorder()
+	replayIdempotentResponse(w, &idempotencyEntry{
+		Status:  http.StatusCreated,
+		Headers: []byte(`{"Content-Type":["application/json"]}`),
+		Body:    []byte(`{"id":1}`),
+	})
+
+	if w.Code != http.StatusCreated || w.Body.String() != `{"id":1}` {
+		t.Error("incorrect replay", w.Code, w.Body.String())
+	}
+
+	if w.Header().Get("Content-Type") != "application/json" || w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("incorrect replay headers", w.Header())
+	}
+}
+
+func TestIdempotencyHandler(t *testing.T) {
+	requireDB(t)
+
+	idempotency, err := IdempotencyHandler(&IdempotencyOpts{CleanupInterval: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	status := http.StatusCreated
+
+	handler := AttachTxHandler()(idempotency(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `{"call":%d,"input":%s}`, calls, body)
+	})))
+
+	post := func(key string, body string) *httptest.ResponseRecorder {
+		rq := httptest.NewRequest("POST", "/api/order", strings.NewReader(body))
+		rq.Header.Set("Idempotency-Key", key)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, rq)
+		return w
+	}
+
+	newKey := func() string {
+		return "test-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	}
+
+	t.Run("replay", func(t *testing.T) {
+		key := newKey()
+		calls = 0
+
+		first := post(key, `{"id":1}`)
+		if first.Code != http.StatusCreated || first.Body.String() != `{"call":1,"input":{"id":1}}` {
+			t.Fatal("unexpected response", first.Code, first.Body.String())
+		}
+
+		retry := post(key, `{"id":1}`)
+		if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() || calls != 1 {
+			t.Fatal("expected the saved response to be replayed", retry.Code, retry.Body.String(), calls)
+		}
+
+		if retry.Header().Get("Idempotent-Replayed") != "true" || retry.Header().Get("Content-Type") != "application/json" {
+			t.Error("unexpected replay headers", retry.Header())
+		}
+
+		if w := post(key, `{"id":2}`); w.Code != http.StatusUnprocessableEntity || calls != 1 {
+			t.Error("expected a different request with the same key to be rejected", w.Code, calls)
+		}
+	})
+
+	t.Run("in progress", func(t *testing.T) {
+		key := newKey()
+		calls = 0
+
+		// another instance is handling the same key
+		ctx, cancel, err := BeginTx(context.Background(), "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer cancel()
+
+		if acquired, err := TryAdvisoryLockTx(ctx, idempotencyLockKey("", key)); err != nil || !acquired {
+			t.Fatal("failed to take the lock", acquired, err)
+		}
+
+		if w := post(key, `{"id":1}`); w.Code != http.StatusConflict || calls != 0 {
+			t.Fatal("expected 409 while the key is locked", w.Code, calls)
+		}
+
+		cancel()
+
+		if w := post(key, `{"id":1}`); w.Code != http.StatusCreated || calls != 1 {
+			t.Fatal("expected the request to run once the lock is released", w.Code, calls)
+		}
+	})
+
+	t.Run("errors aren't saved", func(t *testing.T) {
+		key := newKey()
+		calls = 0
+
+		status = http.StatusBadRequest
+		defer func() {
+			status = http.StatusCreated
+		}()
+
+		if w := post(key, `{"id":1}`); w.Code != http.StatusBadRequest {
+			t.Fatal("unexpected status", w.Code)
+		}
+
+		w := post(key, `{"id":1}`)
+		if w.Code != http.StatusBadRequest || calls != 2 || w.Header().Get("Idempotent-Replayed") != "" {
+			t.Fatal("expected the handler to run again after an error", w.Code, calls)
+		}
+	})
+}
+
+func TestIdempotencyHandlerSaveFails(t *testing.T) {
+	requireDB(t)
+
+	idempotency, err := IdempotencyHandler(&IdempotencyOpts{CleanupInterval: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	// the check constraint rejects every saved response
+	err = WithTx(ctx, func(ctx context.Context, tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, `create table idempotency_test_keys (like `+IdempotencyTable+` including all, check (status < 0));
+			create table idempotency_test_order (key text not null)`)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table := IdempotencyTable
+	IdempotencyTable = "idempotency_test_keys"
+
+	t.Cleanup(func() {
+		IdempotencyTable = table
+		er.Check(WithTx(ctx, func(ctx context.Context, tx *sqlx.Tx) error {
+			_, err := tx.ExecContext(ctx, `drop table idempotency_test_keys; drop table idempotency_test_order`)
+			return err
+		}))
+	})
+
+	handler := AttachTxHandler()(idempotency(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		er.Check(ExecContext(r.Context(), `insert into idempotency_test_order (key) values ($1)`, r.Header.Get("Idempotency-Key")))
+		w.WriteHeader(http.StatusCreated)
+	})))
+
+	rq := httptest.NewRequest("POST", "/api/order", strings.NewReader(`{"id":1}`))
+	rq.Header.Set("Idempotency-Key", "test-save-fails")
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, rq)
+
+	if w.Code != http.StatusCreated {
+		t.Fatal("unexpected status", w.Code)
+	}
+
+	count := 0
+	err = WithTx(ctx, func(ctx context.Context, tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &count, `select count(*) from idempotency_test_order where key = $1`, "test-save-fails")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 1 {
+		t.Error("expected the handler's changes to commit when the response can't be saved, got", count)
+	}
+}
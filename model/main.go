@@ -121,6 +121,16 @@ func UseConnection(ctx context.Context, key string) context.Context {
 	return context.WithValue(ctx, contextKey, key)
 }
 
+// ConnectionKey returns the key of the connection selected by UseConnection (DefaultConnectionKey if none)
+func ConnectionKey(ctx context.Context) string {
+	key, ok := ctx.Value(contextKey).(string)
+	if !ok {
+		return DefaultConnectionKey
+	}
+
+	return key
+}
+
 func OnTransactionCommitted(ctx context.Context, callback func()) {
 	tx := getInfo(ctx)
 	if tx.commitCalled {
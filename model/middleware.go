@@ -4,7 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"net/http"
+	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
 )
 
@@ -13,6 +15,10 @@ import (
 //
 // By default transactions will be cancelled unless Commit()
 // is called.
+//
+// ignorePaths skip the transaction, e.g. for event streams (res.EventStream) that would otherwise hold
+// a pooled connection for as long as the client is connected. They're matched against the request path
+// and, when used with mux's Use, the route's path template (e.g. "/api/jobs/{id}/progress").
 func AttachTxHandler(ignorePaths ...string) func(withTx http.Handler) http.Handler {
 	return func(withTx http.Handler) http.Handler {
 		return &txRouter{withTx: withTx, ignorePaths: ignorePaths}
@@ -23,6 +29,11 @@ type AttachTxHandlerOpts struct {
 	IgnorePaths    []string
 	IsolationLevel sql.IsolationLevel
 	Readonly       bool
+
+	// StatementTimeout and IdleInTransactionTimeout are applied to each request's transaction,
+	// see BeginTx2Options. Timed out queries return *TimeoutError, which res.Error reports as 503/504.
+	StatementTimeout         time.Duration
+	IdleInTransactionTimeout time.Duration
 }
 
 func AttachTxHandler2(opts *AttachTxHandlerOpts) func(withTx http.Handler) http.Handler {
@@ -31,8 +42,10 @@ func AttachTxHandler2(opts *AttachTxHandlerOpts) func(withTx http.Handler) http.
 			withTx:      withTx,
 			ignorePaths: opts.IgnorePaths,
 			transactionOptions: &BeginTx2Options{
-				IsolationLevel: opts.IsolationLevel,
-				Readonly:       opts.Readonly,
+				IsolationLevel:           opts.IsolationLevel,
+				Readonly:                 opts.Readonly,
+				StatementTimeout:         opts.StatementTimeout,
+				IdleInTransactionTimeout: opts.IdleInTransactionTimeout,
 			},
 		}
 	}
@@ -50,11 +63,9 @@ type txRouter struct {
 
 func (router *txRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
-	for _, path := range router.ignorePaths {
-		if r.URL.Path == path {
-			router.withTx.ServeHTTP(w, r)
-			return
-		}
+	if router.isIgnored(r) {
+		router.withTx.ServeHTTP(w, r)
+		return
 	}
 
 	// don't open transactions for websocket connections
@@ -73,13 +84,23 @@ func (router *txRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx, cleanup, err := BeginTx2(ctx, &BeginTx2Options{
-		TraceID:        "tx-router:" + r.Method + r.URL.String(),
-		IsolationLevel: opts.IsolationLevel,
-		Readonly:       opts.Readonly,
+		TraceID:                  "tx-router:" + r.Method + r.URL.String(),
+		IsolationLevel:           opts.IsolationLevel,
+		Readonly:                 opts.Readonly,
+		StatementTimeout:         opts.StatementTimeout,
+		IdleInTransactionTimeout: opts.IdleInTransactionTimeout,
 	})
 
 	if err != nil {
 		verboseError(err)
+
+		// e.g. connection pool exhausted
+		if IsTimeoutError(err) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error": "Unable to start transaction"}`))
+			return
+		}
+
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte(`{"error": "Unable to start transaction"}`))
 		return
@@ -114,6 +135,25 @@ func (router *txRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 }
 
+func (router *txRouter) isIgnored(r *http.Request) bool {
+	if len(router.ignorePaths) == 0 {
+		return false
+	}
+
+	template := ""
+	if route := mux.CurrentRoute(r); route != nil {
+		template, _ = route.GetPathTemplate()
+	}
+
+	for _, path := range router.ignorePaths {
+		if r.URL.Path == path || template == path {
+			return true
+		}
+	}
+
+	return false
+}
+
 type httpWriteWrapper struct {
 	StatusCode int
 	http.ResponseWriter
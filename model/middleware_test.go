@@ -0,0 +1,55 @@
+package model
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/ntbosscher/gobase/res"
+)
+
+func TestAttachTxHandlerEventStream(t *testing.T) {
+	hadTx := map[string]bool{}
+
+	stream := func(rq *res.Request) res.Responder {
+		return res.EventStream(func(ctx context.Context, send res.EventSender) {
+			hadTx[rq.Request().URL.Path] = HasTx(ctx)
+			send("status", "ok")
+		})
+	}
+
+	router := mux.NewRouter()
+	router.Use(AttachTxHandler("/api/jobs/{id}/progress"))
+	router.Methods("GET").Path("/api/jobs/{id}/progress").HandlerFunc(res.WrapHTTPFunc(stream))
+	router.Methods("POST").Path("/api/jobs/{id}").HandlerFunc(res.WrapHTTPFunc(stream))
+
+	request := func(method string, path string) *httptest.ResponseRecorder {
+		rq := httptest.NewRequest(method, path, nil)
+		rq.Header.Set("Accept", "text/event-stream")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, rq)
+		return w
+	}
+
+	w := request("GET", "/api/jobs/1/progress")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "event: status\ndata: ok\n\n") {
+		t.Fatal("unexpected response", w.Code, w.Body.String())
+	}
+
+	if hadTx["/api/jobs/1/progress"] {
+		t.Error("expected the ignored event stream to run without a transaction")
+	}
+
+	t.Run("accept header doesn't skip the transaction", func(t *testing.T) {
+		requireDB(t)
+
+		request("POST", "/api/jobs/1")
+		if !hadTx["/api/jobs/1"] {
+			t.Error("expected routes that aren't ignored to get a transaction")
+		}
+	})
+}
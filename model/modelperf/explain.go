@@ -0,0 +1,172 @@
+package modelperf
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/ntbosscher/gobase/er"
+	"github.com/ntbosscher/gobase/model"
+)
+
+// LargeTableRows is the estimated row count (pg_class.reltuples) above which a sequential scan
+// is flagged in Record.SeqScans
+var LargeTableRows float64 = 10000
+
+// ExplainTimeout limits how long a single EXPLAIN may run
+var ExplainTimeout = 5 * time.Second
+
+// maxConcurrentExplains limits the extra connections used for EXPLAIN, slow queries found while
+// all slots are busy aren't explained
+const maxConcurrentExplains = 2
+
+var explainSlots = make(chan bool, maxConcurrentExplains)
+
+// SeqScan is a sequential scan found in a query plan
+type SeqScan struct {
+	Relation      string
+	EstimatedRows float64
+}
+
+var selectStatement = regexp.MustCompile(`(?is)^\s*(\(\s*)*(select|with|values|table)\b`)
+var writeStatement = regexp.MustCompile(`(?i)\b(insert|update|delete|merge)\b`)
+
+// isSelect reports whether query only reads. CTEs are checked for data-modifying statements.
+func isSelect(query string) bool {
+	if !selectStatement.MatchString(query) {
+		return false
+	}
+
+	return !writeStatement.MatchString(shapeString.ReplaceAllString(query, "?"))
+}
+
+func (p *Perf) explain(rec *Record, input *PerfInput) {
+	if input.ExplainSafeMode && !isSelect(rec.Query) {
+		return
+	}
+
+	select {
+	case explainSlots <- true:
+	default:
+		return
+	}
+
+	p.explains.Add(1)
+
+	go func() {
+		defer p.explains.Done()
+		defer func() { <-explainSlots }()
+
+		plan, seqScans, err := runExplain(rec)
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		rec.Plan = plan
+		rec.SeqScans = seqScans
+		rec.ExplainError = err
+	}()
+}
+
+// runExplain runs EXPLAIN (FORMAT JSON) for rec in its own read-only transaction (and therefore
+// connection) that's always rolled back. Postgres only.
+func runExplain(rec *Record) (plan json.RawMessage, seqScans []*SeqScan, err error) {
+	defer er.HandleErrors(func(input *er.HandlerInput) {
+		err = errors.New(input.Message)
+	})
+
+	ctx := model.UseConnection(context.Background(), rec.ConnectionKey)
+	ctx = model.WithoutTenantScope(ctx)
+	ctx, cancel := context.WithTimeout(ctx, ExplainTimeout)
+	defer cancel()
+
+	ctx, rollback, err := model.BeginTx2(ctx, &model.BeginTx2Options{
+		TraceID:          "modelperf-explain",
+		Readonly:         true,
+		StatementTimeout: ExplainTimeout,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer rollback()
+
+	tx := model.Tx(ctx)
+
+	var result []byte
+	if err := tx.QueryRowxContext(ctx, "explain (format json) "+rec.Query, rec.Args...).Scan(&result); err != nil {
+		return nil, nil, err
+	}
+
+	plan = json.RawMessage(result)
+
+	for _, relation := range findSeqScans(plan) {
+		var rows float64
+		err := tx.GetContext(ctx, &rows, `select coalesce(max(reltuples), 0)::float8 from pg_class where oid = to_regclass($1)`, relation)
+		if err != nil {
+			return plan, nil, err
+		}
+
+		if rows >= LargeTableRows {
+			seqScans = append(seqScans, &SeqScan{
+				Relation:      relation,
+				EstimatedRows: rows,
+			})
+		}
+	}
+
+	return plan, seqScans, nil
+}
+
+type planNode struct {
+	NodeType string      `json:"Node Type"`
+	Relation string      `json:"Relation Name"`
+	Schema   string      `json:"Schema"`
+	Plans    []*planNode `json:"Plans"`
+}
+
+// findSeqScans returns the distinct relations that are read with a sequential scan in an
+// EXPLAIN (FORMAT JSON) result
+func findSeqScans(plan json.RawMessage) []string {
+	var root []struct {
+		Plan *planNode `json:"Plan"`
+	}
+
+	if err := json.Unmarshal(plan, &root); err != nil {
+		return nil
+	}
+
+	var list []string
+	seen := map[string]bool{}
+
+	var walk func(node *planNode)
+	walk = func(node *planNode) {
+		if node == nil {
+			return
+		}
+
+		if node.NodeType == "Seq Scan" && node.Relation != "" {
+			name := node.Relation
+			if node.Schema != "" {
+				name = node.Schema + "." + name
+			}
+
+			if !seen[name] {
+				seen[name] = true
+				list = append(list, name)
+			}
+		}
+
+		for _, child := range node.Plans {
+			walk(child)
+		}
+	}
+
+	for _, item := range root {
+		walk(item.Plan)
+	}
+
+	return list
+}
@@ -0,0 +1,38 @@
+package modelperf
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestIsSelect(t *testing.T) {
+	cases := map[string]bool{
+		"select * from a":                                       true,
+		"  (select 1) union (select 2)":                         true,
+		"with x as (select 1) select * from x":                  true,
+		"select * from a where name = 'delete'":                 true,
+		"with x as (delete from a returning *) select * from x": false,
+		"update a set b = 1":                                    false,
+		"insert into a (b) values (1)":                          false,
+	}
+
+	for query, expected := range cases {
+		if isSelect(query) != expected {
+			t.Errorf("isSelect(%q) expected %v", query, expected)
+		}
+	}
+}
+
+func TestFindSeqScans(t *testing.T) {
+	plan := json.RawMessage(`[{"Plan": {"Node Type": "Nested Loop", "Plans": [
+		{"Node Type": "Seq Scan", "Relation Name": "invoice", "Schema": "public"},
+		{"Node Type": "Index Scan", "Relation Name": "customer", "Schema": "public"},
+		{"Node Type": "Hash", "Plans": [{"Node Type": "Seq Scan", "Relation Name": "invoice", "Schema": "public"}]}
+	]}}]`)
+
+	got := findSeqScans(plan)
+	if !reflect.DeepEqual(got, []string{"public.invoice"}) {
+		t.Fatal("unexpected result", got)
+	}
+}
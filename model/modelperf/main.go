@@ -2,8 +2,8 @@ package modelperf
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"github.com/ntbosscher/gobase/er"
 	"github.com/ntbosscher/gobase/model"
 	"io"
 	"sort"
@@ -12,8 +12,14 @@ import (
 )
 
 type Perf struct {
-	rows []*Record
-	mu   sync.RWMutex
+	rows     []*Record
+	mu       sync.RWMutex
+	explains sync.WaitGroup
+}
+
+// WaitExplains blocks until the EXPLAINs started for slow queries (see PerfInput.ExplainThreshold) are done
+func (p *Perf) WaitExplains() {
+	p.explains.Wait()
 }
 
 func (p *Perf) GetRecords() []*Record {
@@ -31,6 +37,10 @@ type Summary struct {
 	AverageDuration time.Duration
 	TotalDuration   time.Duration
 	FirstSeen       time.Time
+
+	// Plan and SeqScans come from the slowest explained call (see PerfInput.ExplainThreshold)
+	Plan     json.RawMessage
+	SeqScans []*SeqScan
 }
 
 type ByTotalDuration []*Summary
@@ -78,6 +88,8 @@ func (p *Perf) GetSummaries() []*Summary {
 			CallCount: len(v),
 		}
 
+		var slowestExplained time.Duration
+
 		for _, rec := range v {
 			if rec.Error != nil {
 				sum.ErrorCount++
@@ -85,6 +97,12 @@ func (p *Perf) GetSummaries() []*Summary {
 
 			dur := rec.End.Sub(rec.Start)
 			sum.TotalDuration += dur
+
+			if rec.Plan != nil && dur > slowestExplained {
+				slowestExplained = dur
+				sum.Plan = rec.Plan
+				sum.SeqScans = rec.SeqScans
+			}
 		}
 
 		if sum.CallCount > 0 {
@@ -116,12 +134,36 @@ type Record struct {
 	Start  time.Time
 	End    time.Time
 	Error  error
+
+	// Caller is the "file:line" that ran the query (see CallerSkipPrefixes)
+	Caller string
+
+	// ConnectionKey is the model connection the query ran on
+	ConnectionKey string
+
+	// Plan is the EXPLAIN (FORMAT JSON) output for queries slower than PerfInput.ExplainThreshold.
+	// It's filled in asynchronously, use Perf.WaitExplains before reading it.
+	Plan json.RawMessage
+
+	// SeqScans are the sequential scans in Plan on tables with at least LargeTableRows rows
+	SeqScans []*SeqScan
+
+	// ExplainError is set when the EXPLAIN failed
+	ExplainError error
 }
 
 type PerfInput struct {
 	// Filter determines which records to keep (true=keep)
 	// pass nil to keep all
 	Filter func(r *Record) bool
+
+	// ExplainThreshold runs EXPLAIN (FORMAT JSON) in the background for queries that take longer
+	// than this, on a separate read-only connection (postgres only). See Record.Plan.
+	// default: 0 (disabled)
+	ExplainThreshold time.Duration
+
+	// ExplainSafeMode only explains SELECT statements
+	ExplainSafeMode bool
 }
 
 // New creates a new performance tracker
@@ -133,41 +175,34 @@ func New(ctx context.Context, input *PerfInput) (context.Context, context.Cancel
 		input = &PerfInput{}
 	}
 
-	inputC := make(chan *Record, 1)
 	ctx, cancel := context.WithCancel(ctx)
 
 	ctx = model.SetHook(ctx, func(ctx context.Context, method string, query string, args []interface{}, resultError error, start time.Time, end time.Time) {
 		rec := &Record{
-			Query: query,
-			Args:  args,
-			Start: start,
-			End:   end,
-			Error: resultError,
+			Method: method,
+			Query:  query,
+			Args:   args,
+			Start:  start,
+			End:    end,
+			Error:  resultError,
+			Caller: callerLocation(2),
+
+			ConnectionKey: model.ConnectionKey(ctx),
 		}
 
 		if input.Filter != nil && !input.Filter(rec) {
 			return
 		}
 
-		select {
-		case inputC <- rec:
-		case <-ctx.Done():
-			er.Throw("context cancelled")
-		}
-	})
+		// recorded synchronously so the records are complete as soon as the request returns
+		p.mu.Lock()
+		p.rows = append(p.rows, rec)
+		p.mu.Unlock()
 
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case v := <-inputC:
-				p.mu.Lock()
-				p.rows = append(p.rows, v)
-				p.mu.Unlock()
-			}
+		if input.ExplainThreshold > 0 && resultError == nil && end.Sub(start) >= input.ExplainThreshold {
+			p.explain(rec, input)
 		}
-	}()
+	})
 
 	return ctx, cancel, p
 }
@@ -0,0 +1,139 @@
+package modelperf
+
+import (
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	shapeString      = regexp.MustCompile(`'(?:[^']|'')*'`)
+	shapePlaceholder = regexp.MustCompile(`\$\d+`)
+	shapeNumber      = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	shapeList        = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+	shapeRows        = regexp.MustCompile(`\(\?\)(?:\s*,\s*\(\?\))+`)
+	shapeSpace       = regexp.MustCompile(`\s+`)
+)
+
+// NormalizeQuery reduces a query to its shape so queries that only differ by literals,
+// placeholder numbers or the length of an "in (...)" list compare equal.
+//
+//	select * from a where id = $1 and b in ($2, $3)  =>  select * from a where id = ? and b in (?)
+func NormalizeQuery(query string) string {
+	query = shapeString.ReplaceAllString(query, "?")
+	query = shapePlaceholder.ReplaceAllString(query, "?")
+	query = shapeNumber.ReplaceAllString(query, "?")
+	query = shapeList.ReplaceAllString(query, "(?)")
+	query = shapeRows.ReplaceAllString(query, "(?)")
+	query = shapeSpace.ReplaceAllString(query, " ")
+
+	return strings.TrimSpace(query)
+}
+
+// CallerSkipPrefixes lists the function-name prefixes skipped when looking for the code that
+// ran a query. Add your own query helper packages so the reported location is the call site.
+var CallerSkipPrefixes = []string{
+	"github.com/ntbosscher/gobase/",
+	"github.com/jmoiron/sqlx",
+	"database/sql",
+	"runtime.",
+}
+
+// callerLocation returns "file:line" of the first frame outside CallerSkipPrefixes. When every frame
+// is skipped (e.g. queries made by gobase itself) the first frame outside the model package is used.
+func callerLocation(skip int) string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(skip+1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	fallback := ""
+
+	for {
+		frame, more := frames.Next()
+
+		if !hasSkipPrefix(frame.Function) {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+
+		if fallback == "" && !isModelFrame(frame.Function) {
+			fallback = frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+
+		if !more {
+			return fallback
+		}
+	}
+}
+
+func hasSkipPrefix(function string) bool {
+	for _, prefix := range CallerSkipPrefixes {
+		if strings.HasPrefix(function, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isModelFrame(function string) bool {
+	return strings.HasPrefix(function, "github.com/ntbosscher/gobase/model.") ||
+		strings.HasPrefix(function, "github.com/ntbosscher/gobase/model/") ||
+		strings.HasPrefix(function, "github.com/jmoiron/sqlx") ||
+		strings.HasPrefix(function, "database/sql") ||
+		strings.HasPrefix(function, "runtime.")
+}
+
+// RepeatedQuery is a query shape that ran several times in the same Perf (usually an N+1)
+type RepeatedQuery struct {
+	Shape         string
+	CallCount     int
+	TotalDuration time.Duration
+
+	// Callers maps "file:line" to the number of calls made from there
+	Callers map[string]int
+}
+
+// GetRepeatedQueries returns the query shapes (see NormalizeQuery) that ran at least minCount times,
+// most frequent first
+func (p *Perf) GetRepeatedQueries(minCount int) []*RepeatedQuery {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	byShape := map[string]*RepeatedQuery{}
+	var list []*RepeatedQuery
+
+	for _, row := range p.rows {
+		shape := NormalizeQuery(row.Query)
+
+		item := byShape[shape]
+		if item == nil {
+			item = &RepeatedQuery{
+				Shape:   shape,
+				Callers: map[string]int{},
+			}
+
+			byShape[shape] = item
+			list = append(list, item)
+		}
+
+		item.CallCount++
+		item.TotalDuration += row.End.Sub(row.Start)
+		item.Callers[row.Caller]++
+	}
+
+	var out []*RepeatedQuery
+	for _, item := range list {
+		if item.CallCount >= minCount {
+			out = append(out, item)
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].CallCount > out[j].CallCount
+	})
+
+	return out
+}
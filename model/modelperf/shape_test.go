@@ -0,0 +1,43 @@
+package modelperf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalizeQuery(t *testing.T) {
+	cases := map[string]string{
+		"select * from a where id = $1":                       "select * from a where id = ?",
+		"select *\n  from a where id = 12 and name = 'it''s'": "select * from a where id = ? and name = ?",
+		"select * from a where id in ($1, $2, $3)":            "select * from a where id in (?)",
+		"insert into a (b) values ($1), ($2), ($3)":           "insert into a (b) values (?)",
+		"select col1 from t2":                                 "select col1 from t2",
+	}
+
+	for input, expected := range cases {
+		if got := NormalizeQuery(input); got != expected {
+			t.Errorf("NormalizeQuery(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
+
+func TestGetRepeatedQueries(t *testing.T) {
+	start := time.Now()
+	p := &Perf{}
+
+	for i := 0; i < 3; i++ {
+		p.rows = append(p.rows, &Record{Query: "select * from a where id = $1", Start: start, End: start.Add(time.Millisecond), Caller: "a.go:10"})
+	}
+
+	p.rows = append(p.rows, &Record{Query: "select * from a where id in ($1, $2)", Start: start, End: start, Caller: "b.go:20"})
+	p.rows = append(p.rows, &Record{Query: "select * from b", Start: start, End: start, Caller: "b.go:30"})
+
+	list := p.GetRepeatedQueries(2)
+	if len(list) != 1 {
+		t.Fatal("expected 1 repeated shape, got", len(list))
+	}
+
+	if list[0].CallCount != 3 || list[0].Callers["a.go:10"] != 3 || list[0].TotalDuration != 3*time.Millisecond {
+		t.Fatal("unexpected result", list[0])
+	}
+}
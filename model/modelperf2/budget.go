@@ -0,0 +1,196 @@
+package modelperf2
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ntbosscher/gobase/model/modelperf"
+)
+
+// Budget limits the queries a single request may run. Zero values aren't checked.
+type Budget struct {
+	// MaxQueries is the total number of queries allowed per request
+	MaxQueries int
+
+	// MaxDuration is the total time allowed to be spent in queries per request
+	MaxDuration time.Duration
+
+	// MaxRepeats is how many times a single query shape (see modelperf.NormalizeQuery) may run per request.
+	// Going over this is almost always an N+1.
+	MaxRepeats int
+}
+
+// DefaultBudget applies to requests that don't match a route passed to SetBudget (nil = no budget)
+var DefaultBudget *Budget
+
+type routeBudget struct {
+	method   string
+	segments []string
+	budget   *Budget
+}
+
+var muBudgets sync.RWMutex
+var budgets []*routeBudget
+
+// SetBudget sets the budget for requests matching pattern. The pattern is an optional method and a
+// mux-style path where {name} matches a single segment. The first matching pattern wins.
+//
+//	modelperf2.SetBudget("GET /api/invoices/{id}", &modelperf2.Budget{MaxQueries: 10, MaxRepeats: 3})
+//	modelperf2.SetBudget("/api/reports/{name}", &modelperf2.Budget{MaxDuration: 2 * time.Second})
+func SetBudget(pattern string, budget *Budget) {
+	method := ""
+	path := pattern
+
+	if parts := strings.Fields(pattern); len(parts) == 2 {
+		method = strings.ToUpper(parts[0])
+		path = parts[1]
+	}
+
+	muBudgets.Lock()
+	defer muBudgets.Unlock()
+
+	budgets = append(budgets, &routeBudget{
+		method:   method,
+		segments: strings.Split(strings.Trim(path, "/"), "/"),
+		budget:   budget,
+	})
+}
+
+func getBudget(method string, path string) *Budget {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	muBudgets.RLock()
+	defer muBudgets.RUnlock()
+
+	for _, item := range budgets {
+		if item.method != "" && item.method != method {
+			continue
+		}
+
+		if matchSegments(item.segments, segments) {
+			return item.budget
+		}
+	}
+
+	return DefaultBudget
+}
+
+func matchSegments(pattern []string, path []string) bool {
+	if len(pattern) != len(path) {
+		return false
+	}
+
+	for i, seg := range pattern {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			continue
+		}
+
+		if seg != path[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// BudgetViolation describes a request that went over its Budget
+type BudgetViolation struct {
+	Request       string
+	Budget        *Budget
+	QueryCount    int
+	TotalDuration time.Duration
+
+	// Problems are the human-readable reasons the budget was exceeded
+	Problems []string
+
+	// Repeated are the query shapes that went over Budget.MaxRepeats
+	Repeated []*modelperf.RepeatedQuery
+}
+
+func (v *BudgetViolation) String() string {
+	sb := &strings.Builder{}
+	sb.WriteString("query budget exceeded for " + v.Request + ": " + strings.Join(v.Problems, ", "))
+
+	for _, item := range v.Repeated {
+		fmt.Fprintf(sb, "\n  %dx %s", item.CallCount, ellipsis(item.Shape, 200))
+
+		for caller, count := range item.Callers {
+			fmt.Fprintf(sb, "\n    %dx %s", count, caller)
+		}
+	}
+
+	return sb.String()
+}
+
+// OnBudgetExceeded is called for every request that goes over its budget.
+// default: logs the violation
+var OnBudgetExceeded = func(v *BudgetViolation) {
+	log.Println("gobase/modelperf2:", v.String())
+}
+
+// TestingT is the subset of testing.TB used by FailTestOnBudget
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// FailTestOnBudget enables query capture and makes budget violations fail t. Call the returned func
+// (e.g. with t.Cleanup) to restore the previous settings.
+//
+//	t.Cleanup(modelperf2.FailTestOnBudget(t))
+func FailTestOnBudget(t TestingT) (restore func()) {
+	prevEnabled := Enabled
+	prevHandler := OnBudgetExceeded
+
+	Enabled = true
+	OnBudgetExceeded = func(v *BudgetViolation) {
+		t.Helper()
+		t.Errorf("%s", v.String())
+	}
+
+	return func() {
+		Enabled = prevEnabled
+		OnBudgetExceeded = prevHandler
+	}
+}
+
+func checkBudget(request string, budget *Budget, perf *modelperf.Perf) *BudgetViolation {
+	if budget == nil {
+		return nil
+	}
+
+	records := perf.GetRecords()
+	v := &BudgetViolation{
+		Request:    request,
+		Budget:     budget,
+		QueryCount: len(records),
+	}
+
+	for _, rec := range records {
+		v.TotalDuration += rec.End.Sub(rec.Start)
+	}
+
+	if budget.MaxQueries > 0 && v.QueryCount > budget.MaxQueries {
+		v.Problems = append(v.Problems, fmt.Sprintf("%d queries (max %d)", v.QueryCount, budget.MaxQueries))
+	}
+
+	if budget.MaxDuration > 0 && v.TotalDuration > budget.MaxDuration {
+		v.Problems = append(v.Problems, fmt.Sprintf("%s in queries (max %s)", v.TotalDuration.Round(time.Millisecond), budget.MaxDuration))
+	}
+
+	if budget.MaxRepeats > 0 {
+		v.Repeated = perf.GetRepeatedQueries(budget.MaxRepeats + 1)
+		if len(v.Repeated) > 0 {
+			v.Problems = append(v.Problems, fmt.Sprintf("%d query shapes repeated more than %d times", len(v.Repeated), budget.MaxRepeats))
+		}
+	}
+
+	if len(v.Problems) == 0 {
+		return nil
+	}
+
+	return v
+}
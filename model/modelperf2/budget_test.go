@@ -0,0 +1,46 @@
+package modelperf2
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetBudget(t *testing.T) {
+	invoice := &Budget{MaxQueries: 5}
+	lines := &Budget{MaxQueries: 10}
+
+	SetBudget("GET /api/invoice/{id}", invoice)
+	SetBudget("/api/invoice/{id}/lines", lines)
+
+	defer func() {
+		budgets = nil
+	}()
+
+	if getBudget("GET", "/api/invoice/12") != invoice {
+		t.Error("expected invoice budget")
+	}
+
+	if getBudget("POST", "/api/invoice/12") != DefaultBudget {
+		t.Error("expected method mismatch to use DefaultBudget")
+	}
+
+	if getBudget("POST", "/api/invoice/12/lines") != lines {
+		t.Error("expected method-less pattern to match")
+	}
+
+	if getBudget("GET", "/api/invoice") != DefaultBudget {
+		t.Error("expected segment count mismatch to use DefaultBudget")
+	}
+}
+
+func TestBudgetViolationString(t *testing.T) {
+	v := &BudgetViolation{
+		Request:       "GET /api/invoice/12",
+		TotalDuration: time.Second,
+		Problems:      []string{"12 queries (max 5)"},
+	}
+
+	if v.String() != "query budget exceeded for GET /api/invoice/12: 12 queries (max 5)" {
+		t.Error("unexpected message", v.String())
+	}
+}
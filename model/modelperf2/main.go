@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"crypto/md5"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/ntbosscher/gobase/env"
@@ -21,9 +23,26 @@ var runPerf = env.OptionalBool("DEBUG_PERF", false)
 var runPerfLogFile = env.Optional("DEBUG_PERF_LOG_FILE", "/tmp/perf.txt")
 var captureAllPerf = env.OptionalBool("DEBUG_PERF_ALL", false)
 
+// Enabled turns on query capture in PerfMiddleware.
+// default: env DEBUG_PERF
+var Enabled = runPerf
+
+// RepeatThreshold is the number of times a query shape has to run in one request to be reported
+// as repeated (a likely N+1) in PerfInfo.Repeated.
+// default: env DEBUG_PERF_REPEAT_THRESHOLD or 5
+var RepeatThreshold = env.OptionalInt("DEBUG_PERF_REPEAT_THRESHOLD", 5)
+
+// ExplainThreshold captures query plans for queries slower than this (see modelperf.PerfInput.ExplainThreshold).
+// Only SELECT statements are explained.
+// default: env DEBUG_PERF_EXPLAIN_MS or 0 (disabled)
+var ExplainThreshold = time.Duration(env.OptionalInt("DEBUG_PERF_EXPLAIN_MS", 0)) * time.Millisecond
+
+// samplesPerRequest limits how many PerfInfo's are kept for each request for Handler
+const samplesPerRequest = 20
+
 func PerfMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		if !runPerf {
+		if !Enabled {
 			next.ServeHTTP(w, req)
 			return
 		}
@@ -34,85 +53,147 @@ func PerfMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		ctx, cancel, perf := modelperf.New(req.Context(), &modelperf.PerfInput{})
+		ctx, cancel, perf := modelperf.New(req.Context(), &modelperf.PerfInput{
+			ExplainThreshold: ExplainThreshold,
+			ExplainSafeMode:  true,
+		})
 		defer cancel()
 
 		req = req.WithContext(ctx)
 		next.ServeHTTP(w, req)
 
-		sum := perf.GetSummaries()
-
 		urlCopy, _ := url.Parse(req.URL.String())
 		urlCopy.RawQuery = ""
 
-		select {
-		case perfC <- &PerfInfo{
-			Request: req.Method + " " + urlCopy.String(),
-			Info:    sum,
-		}:
-		default:
+		request := req.Method + " " + urlCopy.String()
+
+		violation := checkBudget(request, getBudget(req.Method, urlCopy.Path), perf)
+		if violation != nil {
+			OnBudgetExceeded(violation)
 		}
+
+		startCollector()
+
+		// explains finish in the background so they don't hold up the response
+		go func() {
+			perf.WaitExplains()
+
+			info := &PerfInfo{
+				Request:   request,
+				Info:      perf.GetSummaries(),
+				Repeated:  perf.GetRepeatedQueries(RepeatThreshold),
+				Violation: violation,
+			}
+
+			for _, item := range info.Info {
+				info.QueryCount += item.CallCount
+				info.TotalDuration += item.TotalDuration
+			}
+
+			select {
+			case perfC <- info:
+			default:
+			}
+		}()
 	})
 }
 
 type PerfInfo struct {
-	Request string
-	Info    []*modelperf.Summary
+	Request       string                     `json:"request"`
+	Info          []*modelperf.Summary       `json:"info"`
+	QueryCount    int                        `json:"queryCount"`
+	TotalDuration time.Duration              `json:"totalDuration"`
+	Repeated      []*modelperf.RepeatedQuery `json:"repeated,omitempty"`
+	Violation     *BudgetViolation           `json:"violation,omitempty"`
 }
 
 var perfC = make(chan *PerfInfo, 100)
 
+var muMerged sync.RWMutex
+var merged = map[string][]*PerfInfo{}
+
+var collectorOnce sync.Once
+
 func init() {
 	if !runPerf {
 		return
 	}
 
-	go func() {
-		defer er.HandleErrors(func(input *er.HandlerInput) {
-			log.Println(input)
-		})
+	startCollector()
+}
 
-		merged := map[string][]*PerfInfo{}
-		tc := time.NewTicker(5 * time.Second)
+func startCollector() {
+	collectorOnce.Do(func() {
+		go collector()
+	})
+}
 
-		defer tc.Stop()
+func collector() {
+	defer er.HandleErrors(func(input *er.HandlerInput) {
+		log.Println(input)
+	})
 
-		changed := false
+	tc := time.NewTicker(5 * time.Second)
 
-		for {
-			select {
-			case p := <-perfC:
-				merged[p.Request] = append(merged[p.Request], p)
-				changed = true
-			case <-tc.C:
-				if !changed {
-					continue
-				}
+	defer tc.Stop()
+
+	changed := false
 
-				changed = false
-				printPerfMap(merged)
+	for {
+		select {
+		case p := <-perfC:
+			muMerged.Lock()
+			list := append(merged[p.Request], p)
+			if len(list) > samplesPerRequest {
+				list = list[len(list)-samplesPerRequest:]
 			}
+
+			merged[p.Request] = list
+			muMerged.Unlock()
+
+			changed = true
+		case <-tc.C:
+			if !changed || !runPerf {
+				continue
+			}
+
+			changed = false
+			printPerfMap()
 		}
-	}()
+	}
 }
 
-func ellipsis(qr string, max int) string {
-	if len(qr) <= max {
-		return qr
-	}
+// Handler serves the captured results, slowest requests first, as json (or as the
+// DEBUG_PERF_LOG_FILE text with ?format=text). It responds 404 unless Enabled, and shows
+// every query the app runs, so keep it behind an admin-only route.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !Enabled {
+			http.NotFound(w, req)
+			return
+		}
 
-	return qr[:max] + "..."
+		w.Header().Set("Cache-Control", "no-store")
 
-}
+		if req.URL.Query().Get("format") == "text" {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			_, _ = w.Write(perfMapText())
+			return
+		}
 
-func printPerfMap(merged map[string][]*PerfInfo) {
-	buf := &bytes.Buffer{}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(sortedPerfInfo())
+	})
+}
 
-	queries := map[string]string{}
+// sortedPerfInfo returns a copy of the captured results sorted by the slowest sample of each request
+func sortedPerfInfo() [][]*PerfInfo {
+	muMerged.RLock()
+	defer muMerged.RUnlock()
 
 	list := jv.GetMapValues(merged)
 
-	list = jv.Reverse(jv.SortFx(list, func(a []*PerfInfo) float64 {
+	return jv.Reverse(jv.SortFx(list, func(a []*PerfInfo) float64 {
 		times := jv.Mapper(a, func(a *PerfInfo) time.Duration {
 			sum := time.Duration(0)
 
@@ -132,6 +213,27 @@ func printPerfMap(merged map[string][]*PerfInfo) {
 
 		return float64(worstTime)
 	}))
+}
+
+func ellipsis(qr string, max int) string {
+	if len(qr) <= max {
+		return qr
+	}
+
+	return qr[:max] + "..."
+
+}
+
+func printPerfMap() {
+	os.WriteFile(runPerfLogFile, perfMapText(), 0644)
+}
+
+func perfMapText() []byte {
+	buf := &bytes.Buffer{}
+
+	queries := map[string]string{}
+
+	list := sortedPerfInfo()
 
 	for _, infos := range list {
 		buf.WriteString("------------------------------------------------------\n")
@@ -162,11 +264,32 @@ func printPerfMap(merged map[string][]*PerfInfo) {
 			for _, item := range sum {
 				if item.Query == qr.Query {
 					fmt.Fprintln(buf, "avg", qr.AverageDuration.String(), "count", qr.CallCount, "total", qr.TotalDuration.String())
+
+					for _, scan := range item.SeqScans {
+						fmt.Fprintln(buf, "seq scan on", scan.Relation, "estimated rows", scan.EstimatedRows)
+					}
 				}
 			}
 
 			have[qr.Query] = true
 		}
+
+		repeatedPrinted := map[string]bool{}
+
+		for _, item := range infos {
+			for _, rep := range item.Repeated {
+				if repeatedPrinted[rep.Shape] {
+					continue
+				}
+
+				repeatedPrinted[rep.Shape] = true
+				fmt.Fprintln(buf, "repeated", rep.CallCount, "times:", ellipsis(rep.Shape, 200))
+
+				for caller, count := range rep.Callers {
+					fmt.Fprintln(buf, "  ", count, caller)
+				}
+			}
+		}
 	}
 
 	for hash, item := range queries {
@@ -175,5 +298,5 @@ func printPerfMap(merged map[string][]*PerfInfo) {
 		fmt.Fprintln(buf, item)
 	}
 
-	os.WriteFile(runPerfLogFile, buf.Bytes(), 0644)
+	return buf.Bytes()
 }
@@ -0,0 +1,63 @@
+package modeltrace
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+// FileExporter writes one json span per line (json-lines)
+type FileExporter struct {
+	mu     sync.Mutex
+	wr     *bufio.Writer
+	closer io.Closer
+}
+
+// NewFileExporter appends spans to the file at path (created if missing)
+func NewFileExporter(path string) (*FileExporter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileExporter{wr: bufio.NewWriter(f), closer: f}, nil
+}
+
+// NewWriterExporter writes spans to wr (e.g. os.Stdout). wr isn't closed on Shutdown.
+func NewWriterExporter(wr io.Writer) *FileExporter {
+	return &FileExporter{wr: bufio.NewWriter(wr)}
+}
+
+func (f *FileExporter) Export(ctx context.Context, spans []*Span) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	enc := json.NewEncoder(f.wr)
+	enc.SetEscapeHTML(false)
+
+	for _, span := range spans {
+		if err := enc.Encode(span); err != nil {
+			return err
+		}
+	}
+
+	return f.wr.Flush()
+}
+
+func (f *FileExporter) Shutdown(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.wr.Flush(); err != nil {
+		return err
+	}
+
+	if f.closer != nil {
+		return f.closer.Close()
+	}
+
+	return nil
+}
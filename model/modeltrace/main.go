@@ -0,0 +1,264 @@
+// Package modeltrace records every query and transaction operation as a span and sends them to an
+// Exporter. The span layout follows OpenTelemetry (trace id, span id, start/end, db.* attributes and
+// a status) so an exporter can pass them on to an otel collector.
+//
+//	exporter, err := modeltrace.NewFileExporter("/var/log/app/queries.jsonl")
+//	stop := modeltrace.Start(exporter, nil)
+//	defer stop(context.Background())
+package modeltrace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ntbosscher/gobase/lg"
+	"github.com/ntbosscher/gobase/model"
+)
+
+// Span is a single query or transaction operation
+type Span struct {
+	// TraceID is the lg trace key of the context the query ran in (see lg.NewContext)
+	TraceID    string                 `json:"traceId,omitempty"`
+	SpanID     string                 `json:"spanId"`
+	Name       string                 `json:"name"`
+	StartTime  time.Time              `json:"startTime"`
+	EndTime    time.Time              `json:"endTime"`
+	DurationMs float64                `json:"durationMs"`
+	Attributes map[string]interface{} `json:"attributes"`
+	Status     Status                 `json:"status"`
+}
+
+type StatusCode string
+
+const (
+	StatusOk    StatusCode = "OK"
+	StatusError StatusCode = "ERROR"
+)
+
+type Status struct {
+	Code    StatusCode `json:"code"`
+	Message string     `json:"message,omitempty"`
+}
+
+// Exporter sends finished spans somewhere (a file, an otel collector...)
+type Exporter interface {
+	// Export is called from a single goroutine with batches of spans
+	Export(ctx context.Context, spans []*Span) error
+
+	// Shutdown flushes and releases the exporter
+	Shutdown(ctx context.Context) error
+}
+
+type Options struct {
+	// BatchSize is the max number of spans per Export call
+	// default: 100
+	BatchSize int
+
+	// FlushInterval is the longest a span waits before being exported
+	// default: 1s
+	FlushInterval time.Duration
+
+	// QueueSize is the number of spans buffered while the exporter is busy. Spans are dropped
+	// when the queue is full rather than slowing down queries.
+	// default: 2048
+	QueueSize int
+
+	// IncludeArgs adds the query arguments to the span (db.args). They may contain personal
+	// information so they're left out by default.
+	IncludeArgs bool
+
+	// DBSystem is the db.system attribute
+	// default: postgresql
+	DBSystem string
+}
+
+type tracer struct {
+	exporter Exporter
+	opts     Options
+	queue    chan *Span
+	done     chan bool
+	dropped  atomic.Int64
+
+	// mu guards queue against sends after it's closed (hooks in flight during stop)
+	mu     sync.RWMutex
+	closed bool
+}
+
+// Start registers a model.GlobalHook that sends a span for every query to exporter. The returned
+// stop func unregisters the hook, exports the queued spans and shuts the exporter down.
+func Start(exporter Exporter, opts *Options) (stop func(ctx context.Context) error) {
+	t := newTracer(exporter, opts)
+	remove := model.AddGlobalHook(t.hook)
+
+	go t.run()
+
+	var once sync.Once
+
+	return func(ctx context.Context) error {
+		var err error
+
+		once.Do(func() {
+			remove()
+
+			t.mu.Lock()
+			t.closed = true
+			close(t.queue)
+			t.mu.Unlock()
+
+			select {
+			case <-t.done:
+			case <-ctx.Done():
+				err = ctx.Err()
+				return
+			}
+
+			if n := t.dropped.Load(); n > 0 {
+				log.Println("gobase/modeltrace: dropped", n, "spans (queue full)")
+			}
+
+			err = exporter.Shutdown(ctx)
+		})
+
+		return err
+	}
+}
+
+func newTracer(exporter Exporter, opts *Options) *tracer {
+	o := Options{}
+	if opts != nil {
+		o = *opts
+	}
+
+	if o.BatchSize <= 0 {
+		o.BatchSize = 100
+	}
+
+	if o.FlushInterval <= 0 {
+		o.FlushInterval = time.Second
+	}
+
+	if o.QueueSize <= 0 {
+		o.QueueSize = 2048
+	}
+
+	if o.DBSystem == "" {
+		o.DBSystem = "postgresql"
+	}
+
+	return &tracer{
+		exporter: exporter,
+		opts:     o,
+		queue:    make(chan *Span, o.QueueSize),
+		done:     make(chan bool),
+	}
+}
+
+func (t *tracer) hook(ctx context.Context, event *model.QueryEvent) {
+	span := t.newSpan(ctx, event)
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.closed {
+		return
+	}
+
+	select {
+	case t.queue <- span:
+	default:
+		t.dropped.Add(1)
+	}
+}
+
+func (t *tracer) newSpan(ctx context.Context, event *model.QueryEvent) *Span {
+	attrs := map[string]interface{}{
+		"db.system":         t.opts.DBSystem,
+		"db.operation":      event.Method,
+		"db.connection_key": event.ConnectionKey,
+	}
+
+	if event.Query != "" {
+		attrs["db.statement"] = event.Query
+	}
+
+	if event.Rows >= 0 {
+		attrs["db.rows"] = event.Rows
+	}
+
+	if event.TxTraceID != "" {
+		attrs["db.tx_trace_id"] = event.TxTraceID
+	}
+
+	if t.opts.IncludeArgs && len(event.Args) > 0 {
+		attrs["db.args"] = event.Args
+	}
+
+	span := &Span{
+		TraceID:    lg.CurrentKey(ctx),
+		SpanID:     newSpanID(),
+		Name:       event.Method,
+		StartTime:  event.Start,
+		EndTime:    event.End,
+		DurationMs: float64(event.Duration()) / float64(time.Millisecond),
+		Attributes: attrs,
+		Status:     Status{Code: StatusOk},
+	}
+
+	if event.Error != nil {
+		span.Status = Status{Code: StatusError, Message: event.Error.Error()}
+	}
+
+	return span
+}
+
+func newSpanID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (t *tracer) run() {
+	defer close(t.done)
+
+	ticker := time.NewTicker(t.opts.FlushInterval)
+	defer ticker.Stop()
+
+	var batch []*Span
+
+	export := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := t.exporter.Export(ctx, batch); err != nil {
+			log.Println("gobase/modeltrace: export failed:", err)
+		}
+
+		batch = nil
+	}
+
+	for {
+		select {
+		case span, ok := <-t.queue:
+			if !ok {
+				export()
+				return
+			}
+
+			batch = append(batch, span)
+			if len(batch) >= t.opts.BatchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		}
+	}
+}
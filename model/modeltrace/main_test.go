@@ -0,0 +1,72 @@
+package modeltrace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ntbosscher/gobase/lg"
+	"github.com/ntbosscher/gobase/model"
+)
+
+func TestTracerExport(t *testing.T) {
+	buf := &bytes.Buffer{}
+	tr := newTracer(NewWriterExporter(buf), &Options{FlushInterval: time.Hour})
+
+	go tr.run()
+
+	ctx := lg.NewContext(context.Background())
+	start := time.Now()
+
+	tr.hook(ctx, &model.QueryEvent{
+		Method:        "SelectContext",
+		Query:         "select * from person",
+		Args:          []interface{}{1},
+		Start:         start,
+		End:           start.Add(5 * time.Millisecond),
+		Rows:          2,
+		ConnectionKey: model.DefaultConnectionKey,
+	})
+
+	tr.hook(ctx, &model.QueryEvent{Method: "Commit", Error: errors.New("failed"), Rows: -1, Start: start, End: start})
+
+	tr.mu.Lock()
+	tr.closed = true
+	close(tr.queue)
+	tr.mu.Unlock()
+	<-tr.done
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatal("expected 2 lines, got", buf.String())
+	}
+
+	span := &Span{}
+	if err := json.Unmarshal([]byte(lines[0]), span); err != nil {
+		t.Fatal(err)
+	}
+
+	if span.TraceID != lg.CurrentKey(ctx) || span.Name != "SelectContext" || span.DurationMs != 5 || span.Status.Code != StatusOk {
+		t.Fatal("unexpected span", lines[0])
+	}
+
+	if span.Attributes["db.rows"] != float64(2) || span.Attributes["db.statement"] != "select * from person" {
+		t.Fatal("unexpected attributes", span.Attributes)
+	}
+
+	if _, ok := span.Attributes["db.args"]; ok {
+		t.Fatal("args should be left out by default")
+	}
+
+	if err := json.Unmarshal([]byte(lines[1]), span); err != nil {
+		t.Fatal(err)
+	}
+
+	if span.Status.Code != StatusError || span.Status.Message != "failed" {
+		t.Fatal("unexpected span", lines[1])
+	}
+}
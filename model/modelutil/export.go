@@ -0,0 +1,106 @@
+package modelutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/ntbosscher/gobase/encoding/xlsx"
+)
+
+// ToXLSX returns the table as an Excel workbook with a bold header row
+func (t *Table) ToXLSX() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := xlsx.Encode(buf, "Sheet1", t.Headers, t.Rows); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// ToJSON returns the rows as a json array of objects keyed by header (in column order).
+// Values are the same strings used by ToCSV.
+func (t *Table) ToJSON() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString("[")
+
+	for i, row := range t.Rows {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+
+		buf.WriteString("{")
+
+		for j, header := range t.Headers {
+			if j > 0 {
+				buf.WriteString(",")
+			}
+
+			value := ""
+			if j < len(row) {
+				value = row[j]
+			}
+
+			if err := writeJSONString(buf, header); err != nil {
+				return nil, err
+			}
+
+			buf.WriteString(":")
+
+			if err := writeJSONString(buf, value); err != nil {
+				return nil, err
+			}
+		}
+
+		buf.WriteString("}")
+	}
+
+	buf.WriteString("]")
+	return buf.Bytes(), nil
+}
+
+func writeJSONString(buf *bytes.Buffer, value string) error {
+	js, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	buf.Write(js)
+	return nil
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "|", `\|`, "\r\n", "<br>", "\n", "<br>", "\r", "<br>")
+
+// ToMarkdown returns the table as a github-flavored markdown table
+func (t *Table) ToMarkdown() []byte {
+	buf := &bytes.Buffer{}
+
+	writeRow := func(values []string) {
+		buf.WriteString("|")
+
+		for i := range t.Headers {
+			value := ""
+			if i < len(values) {
+				value = values[i]
+			}
+
+			buf.WriteString(" " + markdownEscaper.Replace(value) + " |")
+		}
+
+		buf.WriteString("\n")
+	}
+
+	writeRow(t.Headers)
+
+	buf.WriteString("|")
+	for range t.Headers {
+		buf.WriteString(" --- |")
+	}
+	buf.WriteString("\n")
+
+	for _, row := range t.Rows {
+		writeRow(row)
+	}
+
+	return buf.Bytes()
+}
@@ -0,0 +1,39 @@
+package modelutil
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTableToJSON(t *testing.T) {
+	tbl := &Table{
+		Headers: []string{"name", "id"},
+		Rows:    [][]string{{"a\"b", "1"}, {"c", "2"}},
+	}
+
+	js, err := tbl.ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(js) != `[{"name":"a\"b","id":"1"},{"name":"c","id":"2"}]` {
+		t.Fatal("unexpected json", string(js))
+	}
+
+	var parsed []map[string]string
+	if err := json.Unmarshal(js, &parsed); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTableToMarkdown(t *testing.T) {
+	tbl := &Table{
+		Headers: []string{"name", "note"},
+		Rows:    [][]string{{"a|b", "line1\nline2"}},
+	}
+
+	expected := "| name | note |\n| --- | --- |\n| a\\|b | line1<br>line2 |\n"
+	if string(tbl.ToMarkdown()) != expected {
+		t.Fatal("unexpected markdown", string(tbl.ToMarkdown()))
+	}
+}
@@ -16,6 +16,8 @@ import (
 	"unicode/utf8"
 
 	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
 	"github.com/lib/pq"
 	"github.com/ntbosscher/gobase/encoding/tsv"
 	"github.com/ntbosscher/gobase/er"
@@ -248,11 +250,22 @@ func BuildUpdateWL(ctx context.Context, table string, value interface{}, id int,
 		update[k] = v.Interface()
 	}
 
-	return model.Builder.Update(table).
-		SetMap(update).
-		Where(squirrel.Eq{"id": id})
+	return model.ScopeUpdate(ctx, buildUpdate(tx, table, update, id, value))
 }
 
+// BuildUpdate builds an update for all the columns in value except ignoreFields.
+// The update is scoped with model.ScopeUpdate, so it only touches the current tenant's rows
+// (see model.RegisterTenantTable) and skips soft-deleted rows (see model.RegisterSoftDeleteTable).
+//
+// If value has a field tagged `model:"version"`, the update is restricted to rows with the current
+// version and increments it (optimistic locking). Use UpdateStruct to get model.ErrStaleObject when the
+// row has been changed by someone else.
+//
+//	type Invoice struct {
+//		ID      int
+//		Total   currency.Cents
+//		Version int `model:"version"`
+//	}
 func BuildUpdate(ctx context.Context, table string, value interface{}, id int, ignoreFields ...string) squirrel.UpdateBuilder {
 	update := squirrel.Eq{}
 
@@ -273,25 +286,87 @@ func BuildUpdate(ctx context.Context, table string, value interface{}, id int, i
 		update[k] = v.Interface()
 	}
 
-	return model.Builder.Update(table).
+	return model.ScopeUpdate(ctx, buildUpdate(tx, table, update, id, value))
+}
+
+// VersionTag marks the struct field used for optimistic locking
+const VersionTag = "version"
+
+// versionField finds the field tagged `model:"version"`
+func versionField(tx *sqlx.Tx, value interface{}) (column string, field reflect.Value, ok bool) {
+	typeMap := tx.Mapper.TypeMap(reflect.TypeOf(value))
+
+	for _, fi := range typeMap.Index {
+		if strings.Contains(fi.Path, ".") { // ignore sub properties
+			continue
+		}
+
+		if fi.Field.Tag.Get("model") != VersionTag {
+			continue
+		}
+
+		field = reflectx.FieldByIndexes(reflect.Indirect(reflect.ValueOf(value)), fi.Index)
+		return fi.Name, field, true
+	}
+
+	return "", reflect.Value{}, false
+}
+
+func buildUpdate(tx *sqlx.Tx, table string, update squirrel.Eq, id int, value interface{}) squirrel.UpdateBuilder {
+	column, version, versioned := versionField(tx, value)
+	if versioned {
+		// the version column is managed here, never set from the struct
+		delete(update, column)
+	}
+
+	qr := model.Builder.Update(table).
 		SetMap(update).
 		Where(squirrel.Eq{"id": id})
 
+	if !versioned {
+		return qr
+	}
+
+	return qr.
+		Set(column, squirrel.Expr(column+" + 1")).
+		Where(squirrel.Eq{column: version.Interface()})
+}
+
+// execUpdate runs the update and, for versioned structs, throws model.ErrStaleObject if
+// no rows were updated. On success the struct's version is incremented to match the database.
+func execUpdate(ctx context.Context, table string, value interface{}, id int, qr squirrel.UpdateBuilder) {
+	_, field, ok := versionField(model.Tx(ctx), value)
+	if !ok {
+		squtil.MustExecContext(ctx, qr)
+		return
+	}
+
+	n, err := squtil.ExecRowsAffected(ctx, qr)
+	er.Check(err)
+
+	if n == 0 {
+		er.Check(&model.StaleObjectError{Table: table, ID: id})
+	}
+
+	if field.CanSet() && field.CanInt() {
+		field.SetInt(field.Int() + 1)
+	}
 }
 
 // UpdateStruct updates the columns based on the struct provided.
+// Throws model.ErrStaleObject if the struct has a `model:"version"` field that no longer matches the row.
 //
 // recommended to use UpdateStructWL instead since structs can change over time and caused unexpected
 // columns to be updated if not specified in the ignoreFields.
 func UpdateStruct(ctx context.Context, table string, value interface{}, id int, ignoreFields ...string) {
 	qr := BuildUpdate(ctx, table, value, id, ignoreFields...)
-	squtil.MustExecContext(ctx, qr)
+	execUpdate(ctx, table, value, id, qr)
 }
 
 // UpdateStructWL updates the columns specified by allowedFields
 func UpdateStructWL(ctx context.Context, table string, value interface{}, id int, allowedFields ...string) {
 	qr := BuildUpdateWL(ctx, table, value, id, allowedFields...)
-	squtil.MustExecContext(ctx, qr)
+	execUpdate(ctx, table, value, id, qr)
 }
 
 func PrintTable(ctx context.Context, query string, args ...interface{}) {
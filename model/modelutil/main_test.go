@@ -0,0 +1,227 @@
+package modelutil
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+	"github.com/ntbosscher/gobase/auth"
+	"github.com/ntbosscher/gobase/er"
+	"github.com/ntbosscher/gobase/model"
+)
+
+type versionedInvoice struct {
+	ID      int
+	Total   int
+	Version int `model:"version"`
+}
+
+func testMapperTx() *sqlx.Tx {
+	return &sqlx.Tx{Mapper: reflectx.NewMapperFunc("db", model.LowerCamelCaseStructNameMapping)}
+}
+
+func TestBuildUpdateVersioned(t *testing.T) {
+	value := &versionedInvoice{ID: 1, Total: 100, Version: 3}
+	update := squirrel.Eq{"total": 100, "version": 3}
+
+	sql, args, err := buildUpdate(testMapperTx(), "invoice", update, 1, value).ToSql()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "UPDATE invoice SET total = $1, version = version + 1 WHERE id = $2 AND version = $3"
+	if sql != expected {
+		t.Errorf("expected '%s', got '%s'", expected, sql)
+	}
+
+	if !reflect.DeepEqual(args, []interface{}{100, 1, 3}) {
+		t.Error("unexpected args", args)
+	}
+}
+
+func TestBuildUpdateUnversioned(t *testing.T) {
+	value := &struct {
+		ID    int
+		Total int
+	}{ID: 1, Total: 100}
+
+	sql, args, err := buildUpdate(testMapperTx(), "invoice", squirrel.Eq{"total": 100}, 1, value).ToSql()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "UPDATE invoice SET total = $1 WHERE id = $2"
+	if sql != expected {
+		t.Errorf("expected '%s', got '%s'", expected, sql)
+	}
+
+	if !reflect.DeepEqual(args, []interface{}{100, 1}) {
+		t.Error("unexpected args", args)
+	}
+}
+
+func TestUpdateStructVersion(t *testing.T) {
+	ctx := useFakeDB(t)
+
+	fakeDB.rowsAffected = 1
+	value := &versionedInvoice{ID: 1, Total: 100, Version: 3}
+	UpdateStruct(ctx, "invoice", value, value.ID)
+
+	if value.Version != 4 {
+		t.Error("expected the struct's version to be incremented, got", value.Version)
+	}
+
+	if !strings.Contains(fakeDB.lastQuery, "version = version + 1") || !strings.Contains(fakeDB.lastQuery, "AND version = $") {
+		t.Error("expected a versioned update, got", fakeDB.lastQuery)
+	}
+}
+
+func TestUpdateStructStale(t *testing.T) {
+	ctx := useFakeDB(t)
+
+	fakeDB.rowsAffected = 0
+	value := &versionedInvoice{ID: 1, Total: 100, Version: 3}
+
+	var input *er.HandlerInput
+	func() {
+		defer er.HandleErrors(func(value *er.HandlerInput) {
+			input = value
+		})
+
+		UpdateStruct(ctx, "invoice", value, value.ID)
+	}()
+
+	if input == nil {
+		t.Fatal("expected a stale update to throw")
+	}
+
+	if !errors.Is(input.Error, model.ErrStaleObject) {
+		t.Error("expected model.ErrStaleObject, got", input.Error)
+	}
+
+	if input.SuggestedHttpCode != http.StatusConflict {
+		t.Error("expected 409, got", input.SuggestedHttpCode)
+	}
+
+	if value.Version != 3 {
+		t.Error("expected the version to be unchanged, got", value.Version)
+	}
+}
+
+func TestUpdateStructScoped(t *testing.T) {
+	model.RegisterTenantTable("modelutil_test_invoice")
+
+	ctx := auth.SetUser(useFakeDB(t), &auth.UserInfo{CompanyID: 7})
+
+	fakeDB.rowsAffected = 1
+	value := &struct {
+		ID    int
+		Total int
+	}{ID: 1, Total: 100}
+
+	sql, args, err := BuildUpdate(ctx, "modelutil_test_invoice", value, value.ID).ToSql()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "UPDATE modelutil_test_invoice SET total = $1 WHERE id = $2 AND modelutil_test_invoice.company = $3"
+	if sql != expected {
+		t.Errorf("expected '%s', got '%s'", expected, sql)
+	}
+
+	if !reflect.DeepEqual(args, []interface{}{100, 1, 7}) {
+		t.Error("unexpected args", args)
+	}
+
+	UpdateStruct(ctx, "modelutil_test_invoice", value, value.ID)
+	if strings.Count(fakeDB.lastQuery, "company = $") != 1 {
+		t.Error("expected the tenant filter once, got", fakeDB.lastQuery)
+	}
+}
+
+// fakeDB records the last statement and reports rowsAffected for every exec
+var fakeDB = &fakeDriver{}
+
+var fakeDBOnce sync.Once
+
+func useFakeDB(t *testing.T) context.Context {
+	fakeDBOnce.Do(func() {
+		sql.Register("modelutil-test", fakeDB)
+		if err := model.AddConnection("modelutil-test", "modelutil-test", ""); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	ctx := model.UseConnection(context.Background(), "modelutil-test")
+	ctx, cancel, err := model.BeginTx(ctx, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(cancel)
+	return ctx
+}
+
+type fakeDriver struct {
+	lastQuery    string
+	rowsAffected int64
+}
+
+func (f *fakeDriver) Open(name string) (driver.Conn, error) {
+	return &fakeConn{driver: f}, nil
+}
+
+type fakeConn struct {
+	driver *fakeDriver
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{driver: c.driver, query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	return nil
+}
+
+type fakeStmt struct {
+	driver *fakeDriver
+	query  string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.driver.lastQuery = s.query
+	return driver.RowsAffected(s.driver.rowsAffected), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("fake driver doesn't support queries")
+}
@@ -0,0 +1,382 @@
+package modelutil
+
+import (
+	"container/list"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ntbosscher/gobase/er"
+	"github.com/ntbosscher/gobase/model"
+)
+
+// SharedCache is a process-wide cache (unlike Cache/Cache2 which live for a single request).
+// Entries expire after TTL and the least recently used entries are evicted once MaxEntries is reached.
+//
+//	var companySettings = modelutil.NewSharedCache[int, *Settings]("company_settings", &modelutil.SharedCacheOpts{TTL: time.Minute})
+//
+//	settings, err := companySettings.Get(ctx, company, func(ctx context.Context) (*Settings, error) {
+//		return loadSettings(ctx, company)
+//	})
+//
+// Use Invalidate when the underlying rows change. With SetCacheBroadcaster, the invalidation
+// reaches every instance of the app.
+type SharedCache[K comparable, V any] struct {
+	name string
+	opts SharedCacheOpts
+
+	mu      sync.Mutex
+	entries map[K]*list.Element
+	lru     *list.List
+	loading map[K]*sharedCacheCall[V]
+	nowFunc func() time.Time
+}
+
+type SharedCacheOpts struct {
+	// TTL is how long an entry is used before it's loaded again
+	// default: 0 (no expiry)
+	TTL time.Duration
+
+	// MaxEntries evicts the least recently used entry when the cache is full
+	// default: 0 (unlimited)
+	MaxEntries int
+}
+
+type sharedCacheEntry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+type sharedCacheCall[V any] struct {
+	done  chan bool
+	value V
+	err   error
+
+	// stale is set when the key is invalidated while loading
+	stale bool
+}
+
+// NewSharedCache creates a cache. name identifies the cache in cluster-wide invalidations so it
+// has to be unique and the same on every instance. K must be json-encodable when using SetCacheBroadcaster.
+func NewSharedCache[K comparable, V any](name string, opts *SharedCacheOpts) *SharedCache[K, V] {
+	if opts == nil {
+		opts = &SharedCacheOpts{}
+	}
+
+	c := &SharedCache[K, V]{
+		name:    name,
+		opts:    *opts,
+		entries: map[K]*list.Element{},
+		lru:     list.New(),
+		loading: map[K]*sharedCacheCall[V]{},
+		nowFunc: time.Now,
+	}
+
+	registerSharedCache(name, c)
+	return c
+}
+
+// Get returns the cached value for key, calling loader when it's missing or expired. Concurrent
+// calls for the same key share a single loader call (made with the first caller's ctx).
+// Errors aren't cached.
+func (c *SharedCache[K, V]) Get(ctx context.Context, key K, loader func(ctx context.Context) (V, error)) (V, error) {
+	c.mu.Lock()
+
+	if value, ok := c.getLocked(key); ok {
+		c.mu.Unlock()
+		return value, nil
+	}
+
+	if call := c.loading[key]; call != nil {
+		c.mu.Unlock()
+
+		select {
+		case <-call.done:
+			return call.value, call.err
+		case <-ctx.Done():
+			var defaultV V
+			return defaultV, ctx.Err()
+		}
+	}
+
+	call := &sharedCacheCall[V]{done: make(chan bool)}
+	c.loading[key] = call
+	c.mu.Unlock()
+
+	func() {
+		defer er.HandleErrors(func(input *er.HandlerInput) {
+			call.err = errors.New(input.Message)
+		})
+
+		call.value, call.err = loader(ctx)
+	}()
+
+	c.mu.Lock()
+	delete(c.loading, key)
+
+	// skip storing values that were invalidated while loading, they may be stale
+	if call.err == nil && !call.stale {
+		c.setLocked(key, call.value)
+	}
+
+	c.mu.Unlock()
+	close(call.done)
+
+	return call.value, call.err
+}
+
+func (c *SharedCache[K, V]) MustGet(ctx context.Context, key K, loader func(ctx context.Context) (V, error)) V {
+	value, err := c.Get(ctx, key, loader)
+	er.Check(err)
+	return value
+}
+
+// Peek returns the cached value without loading it
+func (c *SharedCache[K, V]) Peek(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.getLocked(key)
+}
+
+func (c *SharedCache[K, V]) Set(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.setLocked(key, value)
+}
+
+// Invalidate removes key from this instance right away, and again from every instance once
+// ctx's transaction commits (so nothing re-caches the old value in between).
+// Without a transaction, the other instances are notified right away.
+func (c *SharedCache[K, V]) Invalidate(ctx context.Context, key K) {
+	c.remove(key)
+
+	msg := &cacheInvalidation{Cache: c.name}
+
+	js, err := json.Marshal(key)
+	if err != nil {
+		log.Println("gobase/modelutil: unable to encode cache key for broadcast:", err)
+	} else {
+		msg.Key = js
+	}
+
+	afterCommit(ctx, func() {
+		c.remove(key)
+		broadcastInvalidation(msg)
+	})
+}
+
+// InvalidateAll empties the cache on every instance (see Invalidate)
+func (c *SharedCache[K, V]) InvalidateAll(ctx context.Context) {
+	c.removeAll()
+
+	afterCommit(ctx, func() {
+		c.removeAll()
+		broadcastInvalidation(&cacheInvalidation{Cache: c.name, All: true})
+	})
+}
+
+func afterCommit(ctx context.Context, callback func()) {
+	if model.HasTx(ctx) {
+		model.OnTransactionCommitted(ctx, callback)
+		return
+	}
+
+	callback()
+}
+
+func (c *SharedCache[K, V]) getLocked(key K) (V, bool) {
+	var defaultV V
+
+	el := c.entries[key]
+	if el == nil {
+		return defaultV, false
+	}
+
+	entry := el.Value.(*sharedCacheEntry[K, V])
+	if !entry.expires.IsZero() && !c.nowFunc().Before(entry.expires) {
+		c.lru.Remove(el)
+		delete(c.entries, key)
+		return defaultV, false
+	}
+
+	c.lru.MoveToFront(el)
+	return entry.value, true
+}
+
+func (c *SharedCache[K, V]) setLocked(key K, value V) {
+	entry := &sharedCacheEntry[K, V]{key: key, value: value}
+	if c.opts.TTL > 0 {
+		entry.expires = c.nowFunc().Add(c.opts.TTL)
+	}
+
+	if el := c.entries[key]; el != nil {
+		el.Value = entry
+		c.lru.MoveToFront(el)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(entry)
+
+	if c.opts.MaxEntries > 0 && c.lru.Len() > c.opts.MaxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*sharedCacheEntry[K, V]).key)
+	}
+}
+
+func (c *SharedCache[K, V]) remove(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if call := c.loading[key]; call != nil {
+		call.stale = true
+	}
+
+	if el := c.entries[key]; el != nil {
+		c.lru.Remove(el)
+		delete(c.entries, key)
+	}
+}
+
+func (c *SharedCache[K, V]) removeAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, call := range c.loading {
+		call.stale = true
+	}
+
+	c.entries = map[K]*list.Element{}
+	c.lru.Init()
+}
+
+// removeEncoded handles invalidations from other instances
+func (c *SharedCache[K, V]) removeEncoded(msg *cacheInvalidation) {
+	if msg.All {
+		c.removeAll()
+		return
+	}
+
+	var key K
+	if err := json.Unmarshal(msg.Key, &key); err != nil {
+		log.Println("gobase/modelutil: unable to decode cache key for '"+c.name+"':", err)
+		return
+	}
+
+	c.remove(key)
+}
+
+// CacheBroadcaster carries SharedCache invalidations between instances. pqchan.Broadcaster
+// implements it using postgres notifications:
+//
+//	modelutil.SetCacheBroadcaster(pqchan.Broadcaster)
+type CacheBroadcaster interface {
+	Send(ctx context.Context, name string, value interface{}) error
+	Receive(ctx context.Context, name string) (chan json.RawMessage, error)
+}
+
+// CacheBroadcastChannel is the channel name used with the CacheBroadcaster
+var CacheBroadcastChannel = "modelutil_cache"
+
+type cacheInvalidation struct {
+	Origin string          `json:"origin"`
+	Cache  string          `json:"cache"`
+	Key    json.RawMessage `json:"key,omitempty"`
+	All    bool            `json:"all,omitempty"`
+}
+
+type sharedCacheInvalidator interface {
+	removeEncoded(msg *cacheInvalidation)
+}
+
+var muSharedCaches sync.RWMutex
+var sharedCaches = map[string]sharedCacheInvalidator{}
+var broadcaster CacheBroadcaster
+
+// instanceID lets an instance ignore its own broadcasts
+var instanceID = newInstanceID()
+
+func newInstanceID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func registerSharedCache(name string, c sharedCacheInvalidator) {
+	muSharedCaches.Lock()
+	defer muSharedCaches.Unlock()
+
+	if sharedCaches[name] != nil {
+		panic("gobase/modelutil: a SharedCache named '" + name + "' already exists")
+	}
+
+	sharedCaches[name] = c
+}
+
+// SetCacheBroadcaster enables cluster-wide invalidation for every SharedCache
+func SetCacheBroadcaster(b CacheBroadcaster) error {
+	input, err := b.Receive(context.Background(), CacheBroadcastChannel)
+	if err != nil {
+		return err
+	}
+
+	muSharedCaches.Lock()
+	broadcaster = b
+	muSharedCaches.Unlock()
+
+	go func() {
+		for js := range input {
+			receiveInvalidation(js)
+		}
+	}()
+
+	return nil
+}
+
+func receiveInvalidation(js json.RawMessage) {
+	msg := &cacheInvalidation{}
+	if err := json.Unmarshal(js, msg); err != nil {
+		log.Println("gobase/modelutil: invalid cache invalidation:", err)
+		return
+	}
+
+	if msg.Origin == instanceID {
+		return
+	}
+
+	muSharedCaches.RLock()
+	c := sharedCaches[msg.Cache]
+	muSharedCaches.RUnlock()
+
+	if c != nil {
+		c.removeEncoded(msg)
+	}
+}
+
+func broadcastInvalidation(msg *cacheInvalidation) {
+	muSharedCaches.RLock()
+	b := broadcaster
+	muSharedCaches.RUnlock()
+
+	if b == nil || (!msg.All && msg.Key == nil) {
+		return
+	}
+
+	msg.Origin = instanceID
+
+	// the request context may be done by the time the transaction commits
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := b.Send(ctx, CacheBroadcastChannel, msg); err != nil {
+		log.Println("gobase/modelutil: unable to broadcast cache invalidation:", err)
+	}
+}
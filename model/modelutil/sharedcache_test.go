@@ -0,0 +1,116 @@
+package modelutil
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSharedCacheSingleFlight(t *testing.T) {
+	c := NewSharedCache[int, string]("test_single_flight", nil)
+
+	var calls int32
+	release := make(chan bool)
+
+	wait := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+
+			value, err := c.Get(context.Background(), 1, func(ctx context.Context) (string, error) {
+				atomic.AddInt32(&calls, 1)
+				<-release
+				return "one", nil
+			})
+
+			if err != nil || value != "one" {
+				t.Error("unexpected result", value, err)
+			}
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wait.Wait()
+
+	if calls != 1 {
+		t.Fatal("expected 1 loader call, got", calls)
+	}
+}
+
+func TestSharedCacheTTLAndLRU(t *testing.T) {
+	c := NewSharedCache[int, int]("test_ttl_lru", &SharedCacheOpts{TTL: time.Minute, MaxEntries: 2})
+
+	now := time.Now()
+	c.nowFunc = func() time.Time { return now }
+
+	c.Set(1, 1)
+	c.Set(2, 2)
+	c.Peek(1) // 2 is now least recently used
+	c.Set(3, 3)
+
+	if _, ok := c.Peek(2); ok {
+		t.Error("expected 2 to be evicted")
+	}
+
+	if _, ok := c.Peek(1); !ok {
+		t.Error("expected 1 to be kept")
+	}
+
+	now = now.Add(time.Minute)
+
+	if _, ok := c.Peek(1); ok {
+		t.Error("expected 1 to be expired")
+	}
+}
+
+type fakeBroadcaster struct {
+	sent []interface{}
+	c    chan json.RawMessage
+}
+
+func (f *fakeBroadcaster) Send(ctx context.Context, name string, value interface{}) error {
+	f.sent = append(f.sent, value)
+	return nil
+}
+
+func (f *fakeBroadcaster) Receive(ctx context.Context, name string) (chan json.RawMessage, error) {
+	return f.c, nil
+}
+
+func TestSharedCacheInvalidate(t *testing.T) {
+	c := NewSharedCache[string, int]("test_invalidate", nil)
+	c.Set("a", 1)
+	c.Set("b", 2)
+
+	b := &fakeBroadcaster{c: make(chan json.RawMessage)}
+	if err := SetCacheBroadcaster(b); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		broadcaster = nil
+		close(b.c)
+	}()
+
+	c.Invalidate(context.Background(), "a")
+
+	if _, ok := c.Peek("a"); ok {
+		t.Error("expected a to be removed")
+	}
+
+	if len(b.sent) != 1 || b.sent[0].(*cacheInvalidation).Origin != instanceID {
+		t.Fatal("expected invalidation to be broadcast", b.sent)
+	}
+
+	b.c <- json.RawMessage(`{"origin":"other","cache":"test_invalidate","key":"b"}`)
+	b.c <- json.RawMessage(`{}`) // wait for the previous message to be handled
+
+	if _, ok := c.Peek("b"); ok {
+		t.Error("expected b to be removed by the remote invalidation")
+	}
+}
@@ -0,0 +1,268 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/ntbosscher/gobase/er"
+)
+
+// OutboxTable is the table used by Outbox and the outbox dispatcher.
+// Change it before calling StartOutboxDispatcher if the default name collides with your schema.
+var OutboxTable = "model_outbox"
+
+// OutboxHandler delivers a single outbox entry. ctx is inside the dispatcher's transaction, so
+// anything written through this package (e.g. pqworkqueue.Queue.Add) commits together with the
+// removal of the entry. Don't call Commit/Rollback on ctx.
+//
+// Returning an error (or panicking) leaves the entry in the outbox to be retried later.
+type OutboxHandler = func(ctx context.Context, payload json.RawMessage) error
+
+var muOutbox sync.RWMutex
+var outboxHandlers = map[string]OutboxHandler{}
+
+// buffered(1) so committing transactions never block on the dispatcher
+var outboxSignal = make(chan bool, 1)
+
+// Outbox records payload for topic inside the current transaction. Once the transaction commits, the
+// outbox dispatcher (see StartOutboxDispatcher) delivers the entry to the handler registered for
+// topic. Unlike OnTransactionCommitted, the entry survives a crash between commit and delivery.
+//
+// Delivery is at-least-once: handlers should be idempotent.
+// payload must be json-encodable.
+func Outbox(ctx context.Context, topic string, payload interface{}) error {
+	js, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+
+	err = ExecContext(ctx, `insert into `+OutboxTable+` (topic, payload, created_at, next_attempt_at) values ($1, $2, $3, $4)`,
+		topic, js, now, now)
+	if err != nil {
+		return err
+	}
+
+	OnTransactionCommitted(ctx, notifyOutbox)
+	return nil
+}
+
+func MustOutbox(ctx context.Context, topic string, payload interface{}) {
+	er.Check(Outbox(ctx, topic, payload))
+}
+
+// RegisterOutboxHandler sets the handler for topic. Entries for topics without a handler are
+// retried until one is registered (or OutboxDispatcherOpts.MaxAttempts is reached).
+func RegisterOutboxHandler(topic string, handler OutboxHandler) {
+	muOutbox.Lock()
+	defer muOutbox.Unlock()
+
+	outboxHandlers[topic] = handler
+}
+
+func getOutboxHandler(topic string) OutboxHandler {
+	muOutbox.RLock()
+	defer muOutbox.RUnlock()
+
+	return outboxHandlers[topic]
+}
+
+func notifyOutbox() {
+	select {
+	case outboxSignal <- true:
+	default:
+	}
+}
+
+type OutboxDispatcherOpts struct {
+	// PollInterval is how often the outbox table is checked for entries that were committed
+	// by other instances or are due for a retry.
+	// default: 5s
+	PollInterval time.Duration
+
+	// MaxAttempts stops retrying an entry after this many failed deliveries. The entry is kept
+	// with failed_at set so it can be inspected and re-queued manually.
+	// default: 0 (retry forever)
+	MaxAttempts int
+
+	// RetryDelay determines how long to wait before re-delivering an entry that has failed
+	// attempts times.
+	// default: DefaultOutboxRetryDelay
+	RetryDelay func(attempts int) time.Duration
+
+	// SkipMigrate skips creating OutboxTable on start
+	SkipMigrate bool
+}
+
+// DefaultOutboxRetryDelay backs off exponentially from 1s, capped at 1h
+func DefaultOutboxRetryDelay(attempts int) time.Duration {
+	if attempts <= 0 {
+		return 0
+	}
+
+	if attempts > 12 {
+		return time.Hour
+	}
+
+	delay := time.Second << uint(attempts-1)
+	if delay > time.Hour {
+		return time.Hour
+	}
+
+	return delay
+}
+
+// StartOutboxDispatcher creates OutboxTable (postgres) and starts delivering outbox entries in the background.
+// It's safe to run a dispatcher on every instance, entries are claimed with "for update skip locked".
+func StartOutboxDispatcher(opts *OutboxDispatcherOpts) error {
+	if opts == nil {
+		opts = &OutboxDispatcherOpts{}
+	}
+
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 5 * time.Second
+	}
+
+	if opts.RetryDelay == nil {
+		opts.RetryDelay = DefaultOutboxRetryDelay
+	}
+
+	if !opts.SkipMigrate {
+		if err := migrateOutbox(context.Background()); err != nil {
+			return err
+		}
+	}
+
+	go outboxDispatcher(opts)
+	return nil
+}
+
+func migrateOutbox(ctx context.Context) error {
+	return WithTx(ctx, func(ctx context.Context, tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, `create table if not exists `+OutboxTable+` (
+			id bigserial primary key,
+			topic text not null,
+			payload json not null,
+			created_at timestamp not null,
+			next_attempt_at timestamp not null,
+			attempts int not null default 0,
+			last_error text null,
+			failed_at timestamp null
+		)`)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `create index if not exists ix_`+OutboxTable+`_pending on `+OutboxTable+` (next_attempt_at) where failed_at is null`)
+		return err
+	})
+}
+
+func outboxDispatcher(opts *OutboxDispatcherOpts) {
+	tc := time.NewTicker(opts.PollInterval)
+	defer tc.Stop()
+
+	for {
+		for {
+			more, err := dispatchOutboxEntry(context.Background(), opts)
+			if err != nil {
+				log.Println("gobase/model: outbox:", err)
+				break
+			}
+
+			if !more {
+				break
+			}
+		}
+
+		select {
+		case <-tc.C:
+		case <-outboxSignal:
+		}
+	}
+}
+
+type outboxEntry struct {
+	ID       int64           `db:"id"`
+	Topic    string          `db:"topic"`
+	Payload  json.RawMessage `db:"payload"`
+	Attempts int             `db:"attempts"`
+}
+
+// dispatchOutboxEntry claims and delivers the oldest due entry. The handler runs behind a savepoint
+// so a failed delivery can be rolled back while still recording the attempt in the same transaction.
+func dispatchOutboxEntry(ctx context.Context, opts *OutboxDispatcherOpts) (moreToProcess bool, err error) {
+	err = WithTx(ctx, func(ctx context.Context, tx *sqlx.Tx) error {
+		entry := &outboxEntry{}
+		err := tx.GetContext(ctx, entry, `select id, topic, payload, attempts from `+OutboxTable+`
+			where failed_at is null and next_attempt_at <= $1
+			order by id
+			limit 1
+			for update skip locked`, time.Now().UTC())
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		moreToProcess = true
+
+		if _, err := tx.ExecContext(ctx, `savepoint outbox_delivery`); err != nil {
+			return err
+		}
+
+		deliveryErr := deliverOutboxEntry(ctx, entry)
+		if deliveryErr == nil {
+			if _, err := tx.ExecContext(ctx, `release savepoint outbox_delivery`); err != nil {
+				return err
+			}
+
+			_, err := tx.ExecContext(ctx, `delete from `+OutboxTable+` where id = $1`, entry.ID)
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `rollback to savepoint outbox_delivery`); err != nil {
+			return err
+		}
+
+		attempts := entry.Attempts + 1
+		now := time.Now().UTC()
+
+		var failedAt *time.Time
+		if opts.MaxAttempts > 0 && attempts >= opts.MaxAttempts {
+			failedAt = &now
+		}
+
+		_, err = tx.ExecContext(ctx, `update `+OutboxTable+` set
+			attempts = $1,
+			next_attempt_at = $2,
+			last_error = $3,
+			failed_at = $4
+		where id = $5`, attempts, now.Add(opts.RetryDelay(attempts)), deliveryErr.Error(), failedAt, entry.ID)
+		return err
+	})
+
+	return
+}
+
+func deliverOutboxEntry(ctx context.Context, entry *outboxEntry) (err error) {
+	handler := getOutboxHandler(entry.Topic)
+	if handler == nil {
+		return errors.New("no outbox handler registered for topic '" + entry.Topic + "'")
+	}
+
+	defer er.HandleErrors(func(input *er.HandlerInput) {
+		err = errors.New("outbox handler panic: " + input.Message)
+	})
+
+	return handler(ctx, entry.Payload)
+}
@@ -0,0 +1,242 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestDefaultOutboxRetryDelay(t *testing.T) {
+
+	tests := map[int]time.Duration{
+		0:   0,
+		1:   time.Second,
+		2:   2 * time.Second,
+		5:   16 * time.Second,
+		12:  2048 * time.Second,
+		13:  time.Hour,
+		100: time.Hour,
+	}
+
+	for attempts, expect := range tests {
+		got := DefaultOutboxRetryDelay(attempts)
+		if got != expect {
+			t.Errorf("incorrect delay for %d attempts, expected %s got %s", attempts, expect, got)
+		}
+	}
+}
+
+// useTestOutbox points OutboxTable at an empty table for the test
+func useTestOutbox(t *testing.T) *OutboxDispatcherOpts {
+	requireDB(t)
+
+	ctx := context.Background()
+	table := OutboxTable
+	OutboxTable = "model_outbox_test"
+
+	dropTable := func() error {
+		return WithTx(ctx, func(ctx context.Context, tx *sqlx.Tx) error {
+			_, err := tx.ExecContext(ctx, `drop table if exists `+OutboxTable)
+			return err
+		})
+	}
+
+	t.Cleanup(func() {
+		if err := dropTable(); err != nil {
+			t.Error(err)
+		}
+
+		OutboxTable = table
+	})
+
+	if err := dropTable(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrateOutbox(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	return &OutboxDispatcherOpts{RetryDelay: func(attempts int) time.Duration { return 0 }}
+}
+
+func countOutbox(t *testing.T, where string, args ...interface{}) int {
+	count := 0
+	err := WithTx(context.Background(), func(ctx context.Context, tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &count, `select count(*) from `+OutboxTable+` where `+where, args...)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return count
+}
+
+func TestOutboxCommitOnly(t *testing.T) {
+	useTestOutbox(t)
+
+	ctx, cancel, err := BeginTx(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	MustOutbox(ctx, "outbox-test-commit", 1)
+	cancel()
+
+	if n := countOutbox(t, "topic = $1", "outbox-test-commit"); n != 0 {
+		t.Fatal("expected a rolled back entry to be discarded, got", n)
+	}
+
+	err = WithTx(context.Background(), func(ctx context.Context, tx *sqlx.Tx) error {
+		return Outbox(ctx, "outbox-test-commit", 2)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := countOutbox(t, "topic = $1 and payload::text = '2'", "outbox-test-commit"); n != 1 {
+		t.Fatal("expected the committed entry to be saved, got", n)
+	}
+}
+
+func TestOutboxRetry(t *testing.T) {
+	opts := useTestOutbox(t)
+
+	var payloads []string
+	RegisterOutboxHandler("outbox-test-retry", func(ctx context.Context, payload json.RawMessage) error {
+		payloads = append(payloads, string(payload))
+		if len(payloads) == 1 {
+			return errors.New("unavailable")
+		}
+
+		return nil
+	})
+
+	err := WithTx(context.Background(), func(ctx context.Context, tx *sqlx.Tx) error {
+		return Outbox(ctx, "outbox-test-retry", map[string]int{"id": 1})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if more, err := dispatchOutboxEntry(context.Background(), opts); err != nil || !more {
+		t.Fatal("expected an entry to be dispatched", more, err)
+	}
+
+	if n := countOutbox(t, "attempts = 1 and last_error = 'unavailable'"); n != 1 {
+		t.Fatal("expected the failed attempt to be recorded, got", n)
+	}
+
+	if more, err := dispatchOutboxEntry(context.Background(), opts); err != nil || !more {
+		t.Fatal("expected the entry to be retried", more, err)
+	}
+
+	if n := countOutbox(t, "true"); n != 0 {
+		t.Fatal("expected the delivered entry to be removed, got", n)
+	}
+
+	if len(payloads) != 2 || payloads[0] != `{"id":1}` || payloads[1] != payloads[0] {
+		t.Error("expected the payload to be delivered twice, got", payloads)
+	}
+
+	if more, err := dispatchOutboxEntry(context.Background(), opts); err != nil || more {
+		t.Error("expected the outbox to be empty", more, err)
+	}
+}
+
+func TestOutboxSkipLocked(t *testing.T) {
+	opts := useTestOutbox(t)
+
+	entered := make(chan bool)
+	release := make(chan bool)
+
+	var mu sync.Mutex
+	delivered := map[string]int{}
+
+	RegisterOutboxHandler("outbox-test-skip-locked", func(ctx context.Context, payload json.RawMessage) error {
+		mu.Lock()
+		delivered[string(payload)]++
+		first := len(delivered) == 1
+		mu.Unlock()
+
+		if first {
+			// hold the first entry's row lock while the second dispatcher runs
+			entered <- true
+			<-release
+		}
+
+		return nil
+	})
+
+	err := WithTx(context.Background(), func(ctx context.Context, tx *sqlx.Tx) error {
+		if err := Outbox(ctx, "outbox-test-skip-locked", 1); err != nil {
+			return err
+		}
+
+		return Outbox(ctx, "outbox-test-skip-locked", 2)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := dispatchOutboxEntry(context.Background(), opts)
+		done <- err
+	}()
+
+	<-entered
+
+	if _, err := dispatchOutboxEntry(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if delivered["1"] != 1 || delivered["2"] != 1 {
+		t.Error("expected each entry to be delivered once, got", delivered)
+	}
+
+	if n := countOutbox(t, "true"); n != 0 {
+		t.Error("expected both entries to be removed, got", n)
+	}
+}
+
+func TestOutboxFailedDeliveryRollsBack(t *testing.T) {
+	opts := useTestOutbox(t)
+
+	RegisterOutboxHandler("outbox-test-rollback", func(ctx context.Context, payload json.RawMessage) error {
+		if err := Outbox(ctx, "outbox-test-rollback-side-effect", 1); err != nil {
+			return err
+		}
+
+		return errors.New("failed after writing")
+	})
+
+	err := WithTx(context.Background(), func(ctx context.Context, tx *sqlx.Tx) error {
+		return Outbox(ctx, "outbox-test-rollback", 1)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := dispatchOutboxEntry(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := countOutbox(t, "topic = $1", "outbox-test-rollback-side-effect"); n != 0 {
+		t.Error("expected the handler's writes to be rolled back, got", n)
+	}
+
+	if n := countOutbox(t, "topic = $1 and attempts = 1 and last_error = 'failed after writing'", "outbox-test-rollback"); n != 1 {
+		t.Error("expected the attempt to be recorded outside the savepoint, got", n)
+	}
+}
@@ -11,15 +11,36 @@ import (
 func ExecContext(ctx context.Context, query string, args ...interface{}) error {
 	after := preHook(ctx, "ExecContext", query, args)
 
-	_, err := Tx(ctx).ExecContext(ctx, query, args...)
+	result, err := Tx(ctx).ExecContext(ctx, query, args...)
+	err = classifyError(err)
 
 	reportTxError(ctx, err)
-	after(err)
+	after(err, rowsAffected(result, err))
 
 	verboseLog(err, query, args...)
 	return err
 }
 
+// ExecRowsAffected works just like ExecContext except that it returns the number of rows affected
+func ExecRowsAffected(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	after := preHook(ctx, "ExecRowsAffected", query, args)
+
+	result, err := Tx(ctx).ExecContext(ctx, query, args...)
+
+	var n int64
+	if err == nil {
+		n, err = result.RowsAffected()
+	}
+
+	err = classifyError(err)
+
+	reportTxError(ctx, err)
+	after(err, n)
+
+	verboseLog(err, query, args...)
+	return n, err
+}
+
 // Insert works just like ExecContext except that it returns the inserted id
 //
 // Insert uses QueryRow for postgres b/c the driver doesn't support .LastInsertId()
@@ -29,10 +50,10 @@ func Insert(ctx context.Context, query string, args ...interface{}) (id int64, e
 	if defaultDbType == "postgres" {
 		after := preHook(ctx, "Insert", query, args)
 
-		err = Tx(ctx).QueryRowContext(ctx, query, args...).Scan(&id)
+		err = classifyError(Tx(ctx).QueryRowContext(ctx, query, args...).Scan(&id))
 
 		reportTxError(ctx, err)
-		after(err)
+		after(err, singleRow(err))
 
 		if err != nil {
 			verboseLog(err, query, args...)
@@ -45,15 +66,16 @@ func Insert(ctx context.Context, query string, args ...interface{}) (id int64, e
 	after := preHook(ctx, "Insert", query, args)
 	result, err := Tx(ctx).ExecContext(ctx, query, args...)
 	if err != nil {
+		err = classifyError(err)
 		reportTxError(ctx, err)
-		after(err)
+		after(err, 0)
 		verboseLog(err, query, args...)
 		return
 	}
 
 	id, err = result.LastInsertId()
 	reportTxError(ctx, err)
-	after(err)
+	after(err, rowsAffected(result, err))
 
 	verboseLog(err, query, args...)
 	return
@@ -62,9 +84,9 @@ func Insert(ctx context.Context, query string, args ...interface{}) (id int64, e
 // GetContext runs the query expecting exactly 1 resulting row. That row is scanned into dest.
 func GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
 	after := preHook(ctx, "GetContext", query, args)
-	err := Tx(ctx).GetContext(ctx, dest, query, args...)
+	err := classifyError(Tx(ctx).GetContext(ctx, dest, query, args...))
 	reportTxError(ctx, err)
-	after(err)
+	after(err, singleRow(err))
 	verboseLog(err, query, args...)
 	return err
 }
@@ -73,15 +95,15 @@ func GetContext(ctx context.Context, dest interface{}, query string, args ...int
 // Dest must be a pointer to a array-type (e.g. *[]*Person)
 func SelectContext(ctx context.Context, dest interface{}, sql string, args ...interface{}) error {
 	after := preHook(ctx, "SelectContext", sql, args)
-	err := Tx(ctx).SelectContext(ctx, dest, sql, args...)
+	err := classifyError(Tx(ctx).SelectContext(ctx, dest, sql, args...))
 	if err != nil {
 		reportTxError(ctx, err)
-		after(err)
+		after(err, 0)
 		verboseLog(err, sql, args...)
 		return err
 	}
 
-	after(err)
+	after(err, rowCount(dest))
 	verboseLog(err, sql, args...)
 	return nil
 }
@@ -99,10 +121,32 @@ func QueryRowContext(ctx context.Context, query string, args ...interface{}) *Ro
 		txInfo: info,
 	}
 
-	after(nil)
+	// rows aren't known until Scan
+	after(nil, -1)
 	return rwWrapped
 }
 
+func rowsAffected(result sql.Result, err error) int64 {
+	if err != nil || result == nil {
+		return 0
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return -1
+	}
+
+	return n
+}
+
+func singleRow(err error) int64 {
+	if err != nil {
+		return 0
+	}
+
+	return 1
+}
+
 type Row struct {
 	*sql.Row
 	must   bool
@@ -110,7 +154,7 @@ type Row struct {
 }
 
 func (r *Row) Scan(dest ...interface{}) error {
-	err := r.Row.Scan(dest...)
+	err := classifyError(r.Row.Scan(dest...))
 	r.txInfo.lastError = err
 
 	if r.must {
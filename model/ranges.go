@@ -0,0 +1,415 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DateRange is a postgres daterange. Postgres always returns dates ranges as [lower,upper),
+// so Upper is exclusive. A null Lower/Upper is unbounded.
+type DateRange struct {
+	Lower NullDate
+	Upper NullDate
+
+	// Empty is the postgres 'empty' range
+	Empty bool
+
+	// Valid is false for a sql null
+	Valid bool
+}
+
+// NewDateRange creates the range [lower, upper)
+func NewDateRange(lower Date, upper Date) DateRange {
+	return DateRange{Lower: NewNullDate(lower), Upper: NewNullDate(upper), Valid: true}
+}
+
+// Contains reports whether d is within the range
+func (r DateRange) Contains(d Date) bool {
+	if !r.Valid || r.Empty {
+		return false
+	}
+
+	if r.Lower.Valid && time.Time(d).Before(time.Time(r.Lower.Date)) {
+		return false
+	}
+
+	if r.Upper.Valid && !time.Time(d).Before(time.Time(r.Upper.Date)) {
+		return false
+	}
+
+	return true
+}
+
+func (r *DateRange) Scan(value interface{}) error {
+	*r = DateRange{}
+
+	if value == nil {
+		return nil
+	}
+
+	raw, err := rangeString(value)
+	if err != nil {
+		return err
+	}
+
+	parsed, err := parseRange(raw)
+	if err != nil {
+		return err
+	}
+
+	r.Valid = true
+	r.Empty = parsed.empty
+
+	if parsed.lower != "" {
+		tm, err := time.Parse(dateFormat, parsed.lower)
+		if err != nil {
+			return err
+		}
+
+		// normalize (x,... to [x+1,...
+		if !parsed.lowerInclusive {
+			tm = tm.AddDate(0, 0, 1)
+		}
+
+		r.Lower = NewNullDate(Date(tm))
+	}
+
+	if parsed.upper != "" {
+		tm, err := time.Parse(dateFormat, parsed.upper)
+		if err != nil {
+			return err
+		}
+
+		// normalize ...,y] to ...,y+1)
+		if parsed.upperInclusive {
+			tm = tm.AddDate(0, 0, 1)
+		}
+
+		r.Upper = NewNullDate(Date(tm))
+	}
+
+	return nil
+}
+
+func (r DateRange) Value() (driver.Value, error) {
+	if !r.Valid {
+		return nil, nil
+	}
+
+	if r.Empty {
+		return "empty", nil
+	}
+
+	lower, upper := "", ""
+
+	if r.Lower.Valid {
+		lower = r.Lower.Date.String()
+	}
+
+	if r.Upper.Valid {
+		upper = r.Upper.Date.String()
+	}
+
+	return "[" + lower + "," + upper + ")", nil
+}
+
+type dateRangeJSON struct {
+	Lower NullDate `json:"lower"`
+	Upper NullDate `json:"upper"`
+	Empty bool     `json:"empty,omitempty"`
+}
+
+// MarshalJSON encodes as {"lower": "2024-01-01", "upper": "2024-02-01"} where upper is exclusive
+func (r DateRange) MarshalJSON() ([]byte, error) {
+	if !r.Valid {
+		return []byte(`null`), nil
+	}
+
+	return json.Marshal(dateRangeJSON{Lower: r.Lower, Upper: r.Upper, Empty: r.Empty})
+}
+
+func (r *DateRange) UnmarshalJSON(b []byte) error {
+	*r = DateRange{}
+
+	if string(b) == "null" {
+		return nil
+	}
+
+	v := dateRangeJSON{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	*r = DateRange{Lower: v.Lower, Upper: v.Upper, Empty: v.Empty, Valid: true}
+	return nil
+}
+
+// TimeRange is a postgres tstzrange. A nil Lower/Upper is unbounded.
+type TimeRange struct {
+	Lower          *time.Time
+	Upper          *time.Time
+	LowerInclusive bool
+	UpperInclusive bool
+
+	// Empty is the postgres 'empty' range
+	Empty bool
+
+	// Valid is false for a sql null
+	Valid bool
+}
+
+// NewTimeRange creates the range [lower, upper)
+func NewTimeRange(lower time.Time, upper time.Time) TimeRange {
+	return TimeRange{Lower: &lower, Upper: &upper, LowerInclusive: true, Valid: true}
+}
+
+// Contains reports whether tm is within the range
+func (r TimeRange) Contains(tm time.Time) bool {
+	if !r.Valid || r.Empty {
+		return false
+	}
+
+	if r.Lower != nil && (tm.Before(*r.Lower) || (!r.LowerInclusive && tm.Equal(*r.Lower))) {
+		return false
+	}
+
+	if r.Upper != nil && (tm.After(*r.Upper) || (!r.UpperInclusive && tm.Equal(*r.Upper))) {
+		return false
+	}
+
+	return true
+}
+
+var timestamptzLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07",
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999-07:00:00",
+	time.RFC3339Nano,
+}
+
+func parseTimestamptz(value string) (time.Time, error) {
+	for _, layout := range timestamptzLayouts {
+		if tm, err := time.Parse(layout, value); err == nil {
+			return tm, nil
+		}
+	}
+
+	return time.Time{}, errors.New("invalid timestamp '" + value + "'")
+}
+
+func (r *TimeRange) Scan(value interface{}) error {
+	*r = TimeRange{}
+
+	if value == nil {
+		return nil
+	}
+
+	raw, err := rangeString(value)
+	if err != nil {
+		return err
+	}
+
+	parsed, err := parseRange(raw)
+	if err != nil {
+		return err
+	}
+
+	r.Valid = true
+	r.Empty = parsed.empty
+	r.LowerInclusive = parsed.lowerInclusive
+	r.UpperInclusive = parsed.upperInclusive
+
+	if parsed.lower != "" {
+		tm, err := parseTimestamptz(parsed.lower)
+		if err != nil {
+			return err
+		}
+
+		r.Lower = &tm
+	}
+
+	if parsed.upper != "" {
+		tm, err := parseTimestamptz(parsed.upper)
+		if err != nil {
+			return err
+		}
+
+		r.Upper = &tm
+	}
+
+	return nil
+}
+
+func (r TimeRange) Value() (driver.Value, error) {
+	if !r.Valid {
+		return nil, nil
+	}
+
+	if r.Empty {
+		return "empty", nil
+	}
+
+	sb := &strings.Builder{}
+
+	if r.LowerInclusive && r.Lower != nil {
+		sb.WriteString("[")
+	} else {
+		sb.WriteString("(")
+	}
+
+	if r.Lower != nil {
+		sb.WriteString(`"` + r.Lower.Format(time.RFC3339Nano) + `"`)
+	}
+
+	sb.WriteString(",")
+
+	if r.Upper != nil {
+		sb.WriteString(`"` + r.Upper.Format(time.RFC3339Nano) + `"`)
+	}
+
+	if r.UpperInclusive && r.Upper != nil {
+		sb.WriteString("]")
+	} else {
+		sb.WriteString(")")
+	}
+
+	return sb.String(), nil
+}
+
+type timeRangeJSON struct {
+	Lower          *time.Time `json:"lower"`
+	Upper          *time.Time `json:"upper"`
+	LowerInclusive bool       `json:"lowerInclusive"`
+	UpperInclusive bool       `json:"upperInclusive"`
+	Empty          bool       `json:"empty,omitempty"`
+}
+
+func (r TimeRange) MarshalJSON() ([]byte, error) {
+	if !r.Valid {
+		return []byte(`null`), nil
+	}
+
+	return json.Marshal(timeRangeJSON{
+		Lower:          r.Lower,
+		Upper:          r.Upper,
+		LowerInclusive: r.LowerInclusive,
+		UpperInclusive: r.UpperInclusive,
+		Empty:          r.Empty,
+	})
+}
+
+func (r *TimeRange) UnmarshalJSON(b []byte) error {
+	*r = TimeRange{}
+
+	if string(b) == "null" {
+		return nil
+	}
+
+	v := timeRangeJSON{LowerInclusive: true}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	*r = TimeRange{
+		Lower:          v.Lower,
+		Upper:          v.Upper,
+		LowerInclusive: v.LowerInclusive,
+		UpperInclusive: v.UpperInclusive,
+		Empty:          v.Empty,
+		Valid:          true,
+	}
+
+	return nil
+}
+
+func rangeString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case []byte:
+		return string(v), nil
+	case string:
+		return v, nil
+	default:
+		return "", fmt.Errorf("invalid Scan(%T) for range type", v)
+	}
+}
+
+type rawRange struct {
+	lower          string
+	upper          string
+	lowerInclusive bool
+	upperInclusive bool
+	empty          bool
+}
+
+// parseRange parses the postgres range text format, e.g. [2024-01-01,2024-02-01) or
+// ["2024-01-01 00:00:00+00",). Missing and infinite bounds are returned as "".
+func parseRange(value string) (*rawRange, error) {
+	value = strings.TrimSpace(value)
+
+	if strings.EqualFold(value, "empty") {
+		return &rawRange{empty: true}, nil
+	}
+
+	if len(value) < 3 {
+		return nil, errors.New("invalid range '" + value + "'")
+	}
+
+	out := &rawRange{
+		lowerInclusive: value[0] == '[',
+		upperInclusive: value[len(value)-1] == ']',
+	}
+
+	if (value[0] != '[' && value[0] != '(') || (value[len(value)-1] != ']' && value[len(value)-1] != ')') {
+		return nil, errors.New("invalid range '" + value + "'")
+	}
+
+	bounds, err := splitRangeBounds(value[1 : len(value)-1])
+	if err != nil {
+		return nil, errors.New("invalid range '" + value + "': " + err.Error())
+	}
+
+	out.lower = rangeBound(bounds[0])
+	out.upper = rangeBound(bounds[1])
+
+	return out, nil
+}
+
+// splitRangeBounds splits on the comma that isn't inside quotes
+func splitRangeBounds(value string) ([2]string, error) {
+	inQuotes := false
+
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case '"':
+			inQuotes = !inQuotes
+		case ',':
+			if !inQuotes {
+				return [2]string{value[:i], value[i+1:]}, nil
+			}
+		}
+	}
+
+	return [2]string{}, errors.New("missing ','")
+}
+
+func rangeBound(value string) string {
+	value = strings.TrimSpace(value)
+
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		value = value[1 : len(value)-1]
+		value = strings.ReplaceAll(value, `\"`, `"`)
+		value = strings.ReplaceAll(value, `""`, `"`)
+	}
+
+	if strings.EqualFold(value, "infinity") || strings.EqualFold(value, "-infinity") {
+		return ""
+	}
+
+	return value
+}
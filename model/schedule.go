@@ -2,6 +2,7 @@ package model
 
 import (
 	"context"
+	"errors"
 	"github.com/jmoiron/sqlx"
 	"github.com/ntbosscher/gobase/timeutil"
 	"log"
@@ -14,6 +15,20 @@ import (
 //
 // Panicking from within callbackWithTx is treated as moreToProcess=false, err!=nil and will not break the process loop
 func ScheduleFunc(interval time.Duration, callbackWithTx func(ctx context.Context) (moreToProcess bool, err error)) {
+	ScheduleFunc2(interval, &ScheduleFuncOpts{}, callbackWithTx)
+}
+
+type ScheduleFuncOpts struct {
+	// LeaderLockKey makes the schedule leader-only: each run takes a transaction-scoped advisory lock
+	// (see WithAdvisoryLock) with this key and skips the run if another instance holds it.
+	// default: "" (run on every instance)
+	LeaderLockKey string
+}
+
+// ScheduleFunc2 is ScheduleFunc with options
+//
+//	model.ScheduleFunc2(time.Minute, &model.ScheduleFuncOpts{LeaderLockKey: "send-reminders"}, sendReminders)
+func ScheduleFunc2(interval time.Duration, opts *ScheduleFuncOpts, callbackWithTx func(ctx context.Context) (moreToProcess bool, err error)) {
 	timeutil.ScheduleJob(interval, func() {
 
 		ctx := context.Background()
@@ -23,8 +38,23 @@ func ScheduleFunc(interval time.Duration, callbackWithTx func(ctx context.Contex
 
 		for moreToProcess {
 			err = WithTx(ctx, func(ctx context.Context, tx *sqlx.Tx) error {
-				moreToProcess, err = callbackWithTx(ctx)
-				return err
+				if opts.LeaderLockKey == "" {
+					moreToProcess, err = callbackWithTx(ctx)
+					return err
+				}
+
+				lockErr := WithAdvisoryLock(ctx, opts.LeaderLockKey, func(ctx context.Context) error {
+					more, callbackErr := callbackWithTx(ctx)
+					moreToProcess = more
+					return callbackErr
+				})
+
+				if errors.Is(lockErr, ErrLockNotAcquired) {
+					moreToProcess = false
+					return nil
+				}
+
+				return lockErr
 			})
 
 			if err != nil {
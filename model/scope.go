@@ -0,0 +1,182 @@
+package model
+
+import (
+	"context"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lann/builder"
+)
+
+// Scope applies the registered table conventions to select, update and delete builders:
+//   - tenant filtering for tables registered with RegisterTenantTable
+//   - excluding soft-deleted rows for tables registered with RegisterSoftDeleteTable (select and update only)
+//
+// Tables joined into a select are tenant filtered too, soft-delete filtering only applies to the main table.
+// When TenantScopeDebug is on, a registered table that can't be filtered (e.g. in a FULL JOIN) panics.
+// Other Sqlizers are returned unchanged.
+// The squtil helpers call this for every query.
+func Scope(ctx context.Context, qr sq.Sqlizer) sq.Sqlizer {
+	switch v := qr.(type) {
+	case sq.SelectBuilder:
+		return ScopeSelect(ctx, v)
+	case sq.UpdateBuilder:
+		return ScopeUpdate(ctx, v)
+	case sq.DeleteBuilder:
+		return ScopeDelete(ctx, v)
+	default:
+		return qr
+	}
+}
+
+// ScopeSelect scopes the main table, the tables in a multi-part from-expression
+// ("invoice i join customer c on ...") and the tables added with Join/LeftJoin etc.
+func ScopeSelect(ctx context.Context, qr sq.SelectBuilder) sq.SelectBuilder {
+	from, ok := builder.Get(qr, "From")
+	if !ok || from == nil {
+		return scopeJoins(ctx, qr)
+	}
+
+	fromSql, _, err := from.(sq.Sqlizer).ToSql()
+	if err != nil {
+		return qr
+	}
+
+	for i, ref := range splitFromExpr(fromSql) {
+		if i == 0 {
+			for _, pred := range scopePredicates(ctx, ref.table, true) {
+				qr = qr.Where(pred)
+			}
+
+			continue
+		}
+
+		pred, ok := tenantPredicate(ctx, ref.table)
+		if !ok {
+			continue
+		}
+
+		if ref.isOuter() {
+			// the join condition is part of the from string, so there's nowhere to add the filter
+			unscopableTenantTable(ctx, ref.table)
+			continue
+		}
+
+		qr = qr.Where(pred)
+	}
+
+	return scopeJoins(ctx, qr)
+}
+
+// scopeJoins adds the tenant filter for tables added with Join, LeftJoin etc.
+// Inner joins are filtered in the where clause, left joins in the join condition
+// (so rows without a match are still returned).
+func scopeJoins(ctx context.Context, qr sq.SelectBuilder) sq.SelectBuilder {
+	value, _ := builder.Get(qr, "Joins")
+	joins, _ := value.([]sq.Sqlizer)
+	if len(joins) == 0 {
+		return qr
+	}
+
+	var where []sq.Sqlizer
+	changed := false
+
+	for i, join := range joins {
+		joinSql, args, err := join.ToSql()
+		if err != nil {
+			continue
+		}
+
+		refs := splitFromExpr(joinSql)
+		if len(refs) != 2 || strings.TrimSpace(refs[0].table) != "" {
+			continue
+		}
+
+		ref := refs[1]
+		pred, ok := tenantPredicate(ctx, ref.table)
+		if !ok {
+			continue
+		}
+
+		if !ref.isOuter() {
+			where = append(where, pred)
+			continue
+		}
+
+		if !ref.isLeft() || ref.condition == "" {
+			// full joins and left joins with "using" can't be filtered without changing the result
+			unscopableTenantTable(ctx, ref.table)
+			continue
+		}
+
+		predSql, predArgs, err := pred.ToSql()
+		if err != nil {
+			continue
+		}
+
+		joinArgs := append(append([]interface{}{}, args...), predArgs...)
+		joins[i] = sq.Expr(ref.join+" "+ref.table+" ON ("+ref.condition+") AND "+predSql, joinArgs...)
+		changed = true
+	}
+
+	if changed {
+		qr = builder.Delete(qr, "Joins").(sq.SelectBuilder)
+		for _, join := range joins {
+			qr = qr.JoinClause(join)
+		}
+	}
+
+	for _, pred := range where {
+		qr = qr.Where(pred)
+	}
+
+	return qr
+}
+
+// updateScopedKey marks updates that were already scoped (e.g. by modelutil.BuildUpdate) so running
+// them through squtil doesn't add the filters twice. builder ignores lower case names when building the struct.
+const updateScopedKey = "gobaseScoped"
+
+// ScopeUpdate scopes the updated table, updates that have already been scoped are returned unchanged
+func ScopeUpdate(ctx context.Context, qr sq.UpdateBuilder) sq.UpdateBuilder {
+	if scoped, _ := builder.Get(qr, updateScopedKey); scoped == true {
+		return qr
+	}
+
+	table, _ := builder.Get(qr, "Table")
+	tableStr, _ := table.(string)
+
+	for _, pred := range scopePredicates(ctx, tableStr, true) {
+		qr = qr.Where(pred)
+	}
+
+	return builder.Set(qr, updateScopedKey, true).(sq.UpdateBuilder)
+}
+
+// ScopeDelete only applies the tenant filter, hard-deleting soft-deleted rows is allowed
+func ScopeDelete(ctx context.Context, qr sq.DeleteBuilder) sq.DeleteBuilder {
+	from, _ := builder.Get(qr, "From")
+	fromStr, _ := from.(string)
+
+	for _, pred := range scopePredicates(ctx, fromStr, false) {
+		qr = qr.Where(pred)
+	}
+
+	return qr
+}
+
+func scopePredicates(ctx context.Context, fromExpr string, softDelete bool) []sq.Sqlizer {
+	var list []sq.Sqlizer
+
+	if pred, ok := tenantPredicate(ctx, fromExpr); ok {
+		list = append(list, pred)
+	}
+
+	if softDelete {
+		if pred, ok := softDeletePredicate(ctx, fromExpr); ok {
+			list = append(list, pred)
+		}
+	}
+
+	return list
+}
@@ -0,0 +1,120 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/ntbosscher/gobase/er"
+)
+
+// SoftDeleteColumn is the column used by RegisterSoftDeleteTable when no column is given
+var SoftDeleteColumn = "deleted_at"
+
+var muSoftDelete sync.RWMutex
+var softDeleteTables = map[string]string{}
+
+// RegisterSoftDeleteTable marks table as soft-deleted through a nullable timestamp column
+// (default: SoftDeleteColumn). Selects and updates built with squirrel and run through squtil
+// (including modelutil.BuildUpdate, rqutil.Get/GetList and paginate.Query) skip rows where the
+// column is set. Use IncludeDeleted to see them.
+//
+//	model.RegisterSoftDeleteTable("customer")
+//	model.RegisterSoftDeleteTable("invoice", "archived_at")
+func RegisterSoftDeleteTable(table string, column ...string) {
+	col := SoftDeleteColumn
+	if len(column) > 0 && column[0] != "" {
+		col = column[0]
+	}
+
+	muSoftDelete.Lock()
+	defer muSoftDelete.Unlock()
+
+	softDeleteTables[strings.ToLower(table)] = col
+}
+
+func getSoftDeleteColumn(table string) string {
+	muSoftDelete.RLock()
+	defer muSoftDelete.RUnlock()
+
+	return softDeleteTables[strings.ToLower(table)]
+}
+
+type includeDeletedContextKeyType string
+
+const includeDeletedContextKey includeDeletedContextKeyType = "include-deleted"
+
+// IncludeDeleted returns a context where queries include soft-deleted rows
+func IncludeDeleted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, includeDeletedContextKey, true)
+}
+
+// IsIncludeDeleted reports whether ctx came from IncludeDeleted
+func IsIncludeDeleted(ctx context.Context) bool {
+	include, _ := ctx.Value(includeDeletedContextKey).(bool)
+	return include
+}
+
+func softDeletePredicate(ctx context.Context, fromExpr string) (sq.Sqlizer, bool) {
+	if IsIncludeDeleted(ctx) {
+		return nil, false
+	}
+
+	table, alias := splitTableAlias(fromExpr)
+	if table == "" {
+		return nil, false
+	}
+
+	col := getSoftDeleteColumn(table)
+	if col == "" {
+		return nil, false
+	}
+
+	return sq.Eq{alias + "." + col: nil}, true
+}
+
+var ErrNotSoftDeleteTable = errors.New("gobase/model: table isn't registered with RegisterSoftDeleteTable")
+
+// SoftDelete marks the row as deleted. Rows that are already deleted keep their original timestamp.
+func SoftDelete(ctx context.Context, table string, id int) error {
+	col := getSoftDeleteColumn(table)
+	if col == "" {
+		return ErrNotSoftDeleteTable
+	}
+
+	return execScoped(ctx, Builder.Update(table).
+		Set(col, time.Now().UTC()).
+		Where(sq.Eq{"id": id}))
+}
+
+// Restore un-deletes a row deleted with SoftDelete
+func Restore(ctx context.Context, table string, id int) error {
+	col := getSoftDeleteColumn(table)
+	if col == "" {
+		return ErrNotSoftDeleteTable
+	}
+
+	return execScoped(IncludeDeleted(ctx), Builder.Update(table).
+		Set(col, nil).
+		Where(sq.Eq{"id": id}))
+}
+
+func MustSoftDelete(ctx context.Context, table string, id int) {
+	er.Check(SoftDelete(ctx, table, id))
+}
+
+func MustRestore(ctx context.Context, table string, id int) {
+	er.Check(Restore(ctx, table, id))
+}
+
+func execScoped(ctx context.Context, qr sq.UpdateBuilder) error {
+	sqlStr, args, err := ScopeUpdate(ctx, qr).ToSql()
+	if err != nil {
+		return err
+	}
+
+	return ExecContext(ctx, sqlStr, args...)
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"context"
+	"testing"
+)
+
+func TestScopeSoftDelete(t *testing.T) {
+	RegisterSoftDeleteTable("softdelete_test_customer")
+	RegisterSoftDeleteTable("softdelete_test_invoice", "archived_at")
+
+	ctx := context.Background()
+
+	tests := map[string]string{
+		"softdelete_test_customer":  "SELECT id FROM softdelete_test_customer WHERE softdelete_test_customer.deleted_at IS NULL",
+		"softdelete_test_invoice i": "SELECT id FROM softdelete_test_invoice i WHERE i.archived_at IS NULL",
+		"softdelete_test_other":     "SELECT id FROM softdelete_test_other",
+	}
+
+	for from, expect := range tests {
+		sql, _, err := ScopeSelect(ctx, Builder.Select("id").From(from)).ToSql()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if sql != expect {
+			t.Errorf("incorrect sql for '%s', expected '%s' got '%s'", from, expect, sql)
+		}
+	}
+
+	sql, _, _ := Scope(IncludeDeleted(ctx), Builder.Select("id").From("softdelete_test_customer")).ToSql()
+	if sql != "SELECT id FROM softdelete_test_customer" {
+		t.Errorf("expected IncludeDeleted to skip scoping, got '%s'", sql)
+	}
+
+	sql, _, _ = Scope(ctx, Builder.Update("softdelete_test_customer").Set("name", "a").Where("id = ?", 1)).ToSql()
+	if sql != "UPDATE softdelete_test_customer SET name = $1 WHERE id = $2 AND softdelete_test_customer.deleted_at IS NULL" {
+		t.Errorf("unexpected update sql '%s'", sql)
+	}
+
+	sql, _, _ = Scope(ctx, Builder.Delete("softdelete_test_customer").Where("id = ?", 1)).ToSql()
+	if sql != "DELETE FROM softdelete_test_customer WHERE id = $1" {
+		t.Errorf("expected deletes not to be scoped, got '%s'", sql)
+	}
+
+	if SoftDelete(ctx, "softdelete_test_other", 1) != ErrNotSoftDeleteTable {
+		t.Error("expected ErrNotSoftDeleteTable")
+	}
+}
@@ -0,0 +1,51 @@
+package squtil
+
+import (
+	sq "github.com/Masterminds/squirrel"
+	"github.com/ntbosscher/gobase/model"
+)
+
+// These helpers build PostGIS conditions for use with model.Builder. column is inserted into the
+// sql as-is so it must not come from user input. Distances are in meters (the geometries are
+// cast to geography, so they should be SRID 4326).
+//
+//	qr := model.Builder.Select("*").From("store").
+//		Where(squtil.DWithin("location", model.NewGeoPoint(lng, lat), 5000))
+//	qr = squtil.OrderByDistance(qr, "location", model.NewGeoPoint(lng, lat))
+
+// DWithin matches rows where column is within meters of point
+func DWithin(column string, point model.GeoPoint, meters float64) sq.Sqlizer {
+	return sq.Expr("ST_DWithin("+column+"::geography, ?::geography, ?)", point, meters)
+}
+
+// Within matches rows where column is entirely inside polygon
+func Within(column string, polygon model.Polygon) sq.Sqlizer {
+	return sq.Expr("ST_Within("+column+", ?::geometry)", polygon)
+}
+
+// Intersects matches rows where column shares any space with polygon
+func Intersects(column string, polygon model.Polygon) sq.Sqlizer {
+	return sq.Expr("ST_Intersects("+column+", ?::geometry)", polygon)
+}
+
+// InBoundingBox matches rows where column's bounding box overlaps box (uses the gist index)
+func InBoundingBox(column string, box model.BoundingBox) sq.Sqlizer {
+	srid := box.SRID
+	if srid == 0 {
+		srid = model.SRIDWGS84
+	}
+
+	return sq.Expr(column+" && ST_MakeEnvelope(?, ?, ?, ?, ?)", box.MinX, box.MinY, box.MaxX, box.MaxY, srid)
+}
+
+// Distance is a column expression for the distance in meters between column and point
+//
+//	model.Builder.Select("*").Column(squtil.Distance("location", point, "distance"))
+func Distance(column string, point model.GeoPoint, alias string) sq.Sqlizer {
+	return sq.Alias(sq.Expr("ST_Distance("+column+"::geography, ?::geography)", point), alias)
+}
+
+// OrderByDistance sorts nearest first using the index-assisted <-> operator
+func OrderByDistance(qr sq.SelectBuilder, column string, point model.GeoPoint) sq.SelectBuilder {
+	return qr.OrderByClause(column+" <-> ?::geometry", point)
+}
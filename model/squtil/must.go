@@ -12,7 +12,7 @@ import (
 // rows into dest.
 // Dest must be a pointer to a array-type (e.g. *[]*Person)
 func MustSelectContext(ctx context.Context, dest interface{}, qr sq.Sqlizer) {
-	sqlStr, args, err := qr.ToSql()
+	sqlStr, args, err := model.Scope(ctx, qr).ToSql()
 	if err != nil {
 		verboseLog(err, sqlStr, args...)
 		er.Check(err)
@@ -25,7 +25,7 @@ func MustSelectContext(ctx context.Context, dest interface{}, qr sq.Sqlizer) {
 // MustQueryRowContext runs the query and expects exactly 1 row. The results can be collected
 // by calling .Scan() on the result
 func MustQueryRowContext(ctx context.Context, qr sq.Sqlizer) *model.Row {
-	sqlStr, args, err := qr.ToSql()
+	sqlStr, args, err := model.Scope(ctx, qr).ToSql()
 	if err != nil {
 		verboseLog(err, sqlStr, args...)
 		er.Check(err)
@@ -36,7 +36,7 @@ func MustQueryRowContext(ctx context.Context, qr sq.Sqlizer) *model.Row {
 
 // GetContext runs the query expecting exactly 1 resulting row. That row is scanned into dest.
 func MustGetContext(ctx context.Context, dest interface{}, qr sq.Sqlizer) {
-	sqlStr, args, err := qr.ToSql()
+	sqlStr, args, err := model.Scope(ctx, qr).ToSql()
 	if err != nil {
 		verboseLog(err, sqlStr, args...)
 		er.Check(err)
@@ -47,7 +47,7 @@ func MustGetContext(ctx context.Context, dest interface{}, qr sq.Sqlizer) {
 
 // MustExecContext runs the query without expecting any output
 func MustExecContext(ctx context.Context, qr sq.Sqlizer) {
-	sqlStr, args, err := qr.ToSql()
+	sqlStr, args, err := model.Scope(ctx, qr).ToSql()
 	if err != nil {
 		verboseLog(err, sqlStr, args...)
 		er.Check(err)
@@ -61,7 +61,7 @@ func MustExecContext(ctx context.Context, qr sq.Sqlizer) {
 // MustInsert uses QueryRow for postgres b/c the driver doesn't support .LastInsertId()
 // For your postgres insert query, be sure to include "returning <id-column>"
 func MustInsert(ctx context.Context, qr sq.Sqlizer) (id int64) {
-	sqlStr, args, err := qr.ToSql()
+	sqlStr, args, err := model.Scope(ctx, qr).ToSql()
 	if err != nil {
 		verboseLog(err, sqlStr, args...)
 		er.Check(err)
@@ -24,7 +24,7 @@ func verboseLog(err error, query string, args ...interface{}) {
 // rows into dest.
 // Dest must be a pointer to a array-type (e.g. *[]*Person)
 func SelectContext(ctx context.Context, dest interface{}, qr sq.Sqlizer) error {
-	sqlStr, args, err := qr.ToSql()
+	sqlStr, args, err := model.Scope(ctx, qr).ToSql()
 	if err != nil {
 		verboseLog(err, sqlStr, args...)
 		return err
@@ -37,7 +37,7 @@ func SelectContext(ctx context.Context, dest interface{}, qr sq.Sqlizer) error {
 // QueryRowContext runs the query and expects exactly 1 row. The results can be collected
 // by calling .Scan() on the result
 func QueryRowContext(ctx context.Context, qr sq.Sqlizer) *model.Row {
-	sqlStr, args, err := qr.ToSql()
+	sqlStr, args, err := model.Scope(ctx, qr).ToSql()
 	if err != nil {
 		verboseLog(err, sqlStr, args...)
 	}
@@ -47,7 +47,7 @@ func QueryRowContext(ctx context.Context, qr sq.Sqlizer) *model.Row {
 
 // GetContext runs the query expecting exactly 1 resulting row. That row is scanned into dest.
 func GetContext(ctx context.Context, dest interface{}, qr sq.Sqlizer) error {
-	sqlStr, args, err := qr.ToSql()
+	sqlStr, args, err := model.Scope(ctx, qr).ToSql()
 	if err != nil {
 		verboseLog(err, sqlStr, args...)
 		return err
@@ -58,7 +58,7 @@ func GetContext(ctx context.Context, dest interface{}, qr sq.Sqlizer) error {
 
 // ExecContext runs the query without expecting any output
 func ExecContext(ctx context.Context, qr sq.Sqlizer) error {
-	sqlStr, args, err := qr.ToSql()
+	sqlStr, args, err := model.Scope(ctx, qr).ToSql()
 	if err != nil {
 		verboseLog(err, sqlStr, args...)
 		return err
@@ -67,12 +67,23 @@ func ExecContext(ctx context.Context, qr sq.Sqlizer) error {
 	return model.ExecContext(ctx, sqlStr, args...)
 }
 
+// ExecRowsAffected works just like ExecContext except that it returns the number of rows affected
+func ExecRowsAffected(ctx context.Context, qr sq.Sqlizer) (int64, error) {
+	sqlStr, args, err := model.Scope(ctx, qr).ToSql()
+	if err != nil {
+		verboseLog(err, sqlStr, args...)
+		return 0, err
+	}
+
+	return model.ExecRowsAffected(ctx, sqlStr, args...)
+}
+
 // Insert works just like ExecContext except that it returns the inserted id
 //
 // Insert uses QueryRow for postgres b/c the driver doesn't support .LastInsertId()
 // For your postgres insert query, be sure to include "returning <id-column>"
 func Insert(ctx context.Context, qr sq.Sqlizer) (id int64, err error) {
-	sqlStr, args, err := qr.ToSql()
+	sqlStr, args, err := model.Scope(ctx, qr).ToSql()
 	if err != nil {
 		verboseLog(err, sqlStr, args...)
 		return 0, err
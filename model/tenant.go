@@ -0,0 +1,232 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/ntbosscher/gobase/auth"
+	"github.com/ntbosscher/gobase/er"
+)
+
+// TenantColumn is the column used by RegisterTenantTable when no column is given
+var TenantColumn = "company"
+
+// TenantScopeDebug makes every query through this package panic when it reads or writes a table
+// registered with RegisterTenantTable without filtering on the tenant column, or when a scoped
+// query runs without an authenticated company. The check is a text match on the sql, so it's meant
+// to catch mistakes during development and tests, not to be relied on in production.
+var TenantScopeDebug = false
+
+type tenantTable struct {
+	column string
+	// matches "from <table> <alias>", "join <table> as <alias>" etc, capturing the alias
+	reference *regexp.Regexp
+}
+
+var muTenant sync.RWMutex
+var tenantTables = map[string]*tenantTable{}
+
+// RegisterTenantTable marks table as carrying a tenant column (default: TenantColumn).
+// Queries built with the squtil helpers on a registered table are automatically filtered
+// to auth.Company(ctx). Use WithoutTenantScope for admin paths that need to see every tenant.
+//
+//	model.RegisterTenantTable("invoice")
+//	model.RegisterTenantTable("user_account", "tenant_id")
+func RegisterTenantTable(table string, column ...string) {
+	col := TenantColumn
+	if len(column) > 0 && column[0] != "" {
+		col = column[0]
+	}
+
+	muTenant.Lock()
+	defer muTenant.Unlock()
+
+	tenantTables[strings.ToLower(table)] = &tenantTable{
+		column:    col,
+		reference: regexp.MustCompile(`(?i)\b(?:from|join|update)\s+"?` + regexp.QuoteMeta(table) + `"?(?:\s+(?:as\s+)?"?(\w+)"?)?(?:\s|$|\)|,)`),
+	}
+}
+
+func getTenantTable(table string) *tenantTable {
+	muTenant.RLock()
+	defer muTenant.RUnlock()
+
+	return tenantTables[strings.ToLower(table)]
+}
+
+type tenantScopeContextKeyType string
+
+const tenantScopeContextKey tenantScopeContextKeyType = "tenant-scope-disabled"
+
+// WithoutTenantScope returns a context that skips tenant scoping and TenantScopeDebug checks.
+// This is the escape hatch for admin and background paths that legitimately work across tenants.
+func WithoutTenantScope(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tenantScopeContextKey, true)
+}
+
+// IsTenantScopeDisabled reports whether ctx came from WithoutTenantScope
+func IsTenantScopeDisabled(ctx context.Context) bool {
+	disabled, _ := ctx.Value(tenantScopeContextKey).(bool)
+	return disabled
+}
+
+var ErrNoTenant = errors.New("gobase/model: tenant-scoped query without an authenticated company, use model.WithoutTenantScope for admin/background paths")
+
+// tenantPredicate builds "<alias>.<column> = company" for a from-expression like
+// "invoice", "invoice i" or "invoice as i". Subqueries and unregistered tables are ignored.
+func tenantPredicate(ctx context.Context, fromExpr string) (sq.Sqlizer, bool) {
+	if IsTenantScopeDisabled(ctx) {
+		return nil, false
+	}
+
+	table, alias := splitTableAlias(fromExpr)
+	if table == "" {
+		return nil, false
+	}
+
+	info := getTenantTable(table)
+	if info == nil {
+		return nil, false
+	}
+
+	if TenantScopeDebug && auth.Company(ctx) <= 0 {
+		er.Check(ErrNoTenant)
+	}
+
+	return sq.Eq{alias + "." + info.column: auth.Company(ctx)}, true
+}
+
+func splitTableAlias(fromExpr string) (table string, alias string) {
+	parts := strings.Fields(fromExpr)
+	if len(parts) == 0 || strings.HasPrefix(parts[0], "(") {
+		return "", ""
+	}
+
+	table = strings.Trim(parts[0], `"`)
+	alias = parts[0]
+
+	switch {
+	case len(parts) == 2:
+		alias = parts[1]
+	case len(parts) == 3 && strings.EqualFold(parts[1], "as"):
+		alias = parts[2]
+	case len(parts) > 1:
+		return "", ""
+	}
+
+	return table, alias
+}
+
+// checkTenantScope implements TenantScopeDebug for raw sql
+func checkTenantScope(ctx context.Context, query string) {
+	if !TenantScopeDebug || IsTenantScopeDisabled(ctx) {
+		return
+	}
+
+	muTenant.RLock()
+	defer muTenant.RUnlock()
+
+	for table, info := range tenantTables {
+		for _, match := range info.reference.FindAllStringSubmatch(query, -1) {
+			if !tenantFilterPattern(table, match[1], info.column).MatchString(query) {
+				er.Check(errors.New("gobase/model: query on tenant table '" + table + "' is missing a filter on '" + info.column + "': " + query))
+			}
+		}
+	}
+}
+
+// tenantFilterPattern matches "<table or alias>.<column> =" and "<table or alias>.<column> in".
+// Unqualified filters don't count, they could belong to another table in the query.
+func tenantFilterPattern(table string, alias string, column string) *regexp.Regexp {
+	names := regexp.QuoteMeta(table)
+	if alias != "" {
+		names += "|" + regexp.QuoteMeta(alias)
+	}
+
+	return regexp.MustCompile(`(?i)(^|[^\w."])"?(` + names + `)"?\."?` + regexp.QuoteMeta(column) + `"?\s*(=|\bin\b)`)
+}
+
+// unscopableTenantTable panics when TenantScopeDebug is on, for registered tables that Scope can't filter
+func unscopableTenantTable(ctx context.Context, fromExpr string) {
+	if !TenantScopeDebug || IsTenantScopeDisabled(ctx) {
+		return
+	}
+
+	table, _ := splitTableAlias(fromExpr)
+	er.Check(errors.New("gobase/model: can't add the tenant filter for '" + table + "', filter it yourself and use model.WithoutTenantScope"))
+}
+
+// fromRef is one table in a from-expression
+type fromRef struct {
+	join      string // "" for the first table, "," or the join keyword as written e.g. "LEFT JOIN"
+	table     string // table and alias e.g. "customer c"
+	condition string // the ON condition, "" when there isn't one
+}
+
+func (f fromRef) isOuter() bool {
+	kind := strings.ToLower(f.join)
+	return strings.Contains(kind, "left") || strings.Contains(kind, "full")
+}
+
+func (f fromRef) isLeft() bool {
+	return strings.Contains(strings.ToLower(f.join), "left")
+}
+
+var fromJoinPattern = regexp.MustCompile(`(?i)(,|\b(?:natural\s+)?(?:(?:left|right|full)(?:\s+outer)?\s+|inner\s+|cross\s+)?join\b)`)
+var fromConditionPattern = regexp.MustCompile(`(?i)\s(on|using)\b`)
+
+// splitFromExpr splits "invoice i join customer c on c.id = i.customer, payment p" into its tables.
+// Joins and commas inside parentheses (subqueries, function calls) are ignored.
+func splitFromExpr(fromExpr string) []fromRef {
+	masked := maskParens(fromExpr)
+
+	var refs []fromRef
+	join := ""
+	start := 0
+
+	addRef := func(end int) {
+		ref := fromRef{join: join, table: fromExpr[start:end]}
+
+		if loc := fromConditionPattern.FindStringSubmatchIndex(masked[start:end]); loc != nil {
+			ref.table = fromExpr[start : start+loc[0]]
+			if strings.EqualFold(fromExpr[start+loc[2]:start+loc[3]], "on") {
+				ref.condition = strings.TrimSpace(fromExpr[start+loc[1] : end])
+			}
+		}
+
+		ref.table = strings.TrimSpace(ref.table)
+		refs = append(refs, ref)
+	}
+
+	for _, loc := range fromJoinPattern.FindAllStringIndex(masked, -1) {
+		addRef(loc[0])
+		join = strings.Join(strings.Fields(fromExpr[loc[0]:loc[1]]), " ")
+		start = loc[1]
+	}
+
+	addRef(len(fromExpr))
+	return refs
+}
+
+// maskParens blanks out everything inside parentheses, keeping the offsets the same
+func maskParens(value string) string {
+	masked := []byte(value)
+	depth := 0
+
+	for i, c := range masked {
+		switch {
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth > 0:
+			masked[i] = ' '
+		}
+	}
+
+	return string(masked)
+}
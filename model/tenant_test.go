@@ -0,0 +1,174 @@
+package model
+
+import (
+	"context"
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/ntbosscher/gobase/auth"
+)
+
+func TestScopeSelect(t *testing.T) {
+	RegisterTenantTable("tenant_test_invoice")
+
+	ctx := auth.SetUser(context.Background(), &auth.UserInfo{CompanyID: 7})
+
+	tests := map[string]string{
+		"tenant_test_invoice":        "SELECT id FROM tenant_test_invoice WHERE tenant_test_invoice.company = $1",
+		"tenant_test_invoice i":      "SELECT id FROM tenant_test_invoice i WHERE i.company = $1",
+		"tenant_test_invoice as inv": "SELECT id FROM tenant_test_invoice as inv WHERE inv.company = $1",
+		"tenant_test_other":          "SELECT id FROM tenant_test_other",
+	}
+
+	for from, expect := range tests {
+		sql, args, err := ScopeSelect(ctx, Builder.Select("id").From(from)).ToSql()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if sql != expect {
+			t.Errorf("incorrect sql for '%s', expected '%s' got '%s'", from, expect, sql)
+		}
+
+		if len(args) == 1 && args[0] != 7 {
+			t.Errorf("expected company arg 7, got %v", args[0])
+		}
+	}
+
+	sql, _, _ := ScopeSelect(WithoutTenantScope(ctx), Builder.Select("id").From("tenant_test_invoice")).ToSql()
+	if sql != "SELECT id FROM tenant_test_invoice" {
+		t.Errorf("expected WithoutTenantScope to skip scoping, got '%s'", sql)
+	}
+}
+
+func TestScopeSelectJoins(t *testing.T) {
+	RegisterTenantTable("tenant_test_invoice")
+
+	ctx := auth.SetUser(context.Background(), &auth.UserInfo{CompanyID: 7})
+
+	tests := []struct {
+		name   string
+		query  sq.SelectBuilder
+		expect string
+	}{
+		{
+			name:   "join scoped table",
+			query:  Builder.Select("id").From("tenant_test_other o").Join("tenant_test_invoice i on i.id = o.invoice"),
+			expect: "SELECT id FROM tenant_test_other o JOIN tenant_test_invoice i on i.id = o.invoice WHERE i.company = $1",
+		},
+		{
+			name:   "join unscoped table",
+			query:  Builder.Select("id").From("tenant_test_invoice i").Join("tenant_test_other o on o.invoice = i.id"),
+			expect: "SELECT id FROM tenant_test_invoice i JOIN tenant_test_other o on o.invoice = i.id WHERE i.company = $1",
+		},
+		{
+			name:   "left join scoped table",
+			query:  Builder.Select("id").From("tenant_test_other o").LeftJoin("tenant_test_invoice i on i.id = o.invoice"),
+			expect: "SELECT id FROM tenant_test_other o LEFT JOIN tenant_test_invoice i ON (i.id = o.invoice) AND i.company = $1",
+		},
+		{
+			name:   "joins in from",
+			query:  Builder.Select("id").From("tenant_test_other o join tenant_test_invoice i on i.id = o.invoice"),
+			expect: "SELECT id FROM tenant_test_other o join tenant_test_invoice i on i.id = o.invoice WHERE i.company = $1",
+		},
+		{
+			name:   "comma in from",
+			query:  Builder.Select("id").From("tenant_test_invoice i, tenant_test_other o"),
+			expect: "SELECT id FROM tenant_test_invoice i, tenant_test_other o WHERE i.company = $1",
+		},
+	}
+
+	for _, test := range tests {
+		sql, args, err := ScopeSelect(ctx, test.query).ToSql()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if sql != test.expect {
+			t.Errorf("%s: expected '%s' got '%s'", test.name, test.expect, sql)
+		}
+
+		if len(args) != 1 || args[0] != 7 {
+			t.Errorf("%s: expected company arg 7, got %v", test.name, args)
+		}
+	}
+}
+
+func TestScopeSelectUnscopable(t *testing.T) {
+	RegisterTenantTable("tenant_test_invoice")
+
+	ctx := auth.SetUser(context.Background(), &auth.UserInfo{CompanyID: 7})
+	query := Builder.Select("id").From("tenant_test_other o").JoinClause("FULL JOIN tenant_test_invoice i on i.id = o.invoice")
+
+	sql, _, _ := ScopeSelect(ctx, query).ToSql()
+	if sql != "SELECT id FROM tenant_test_other o FULL JOIN tenant_test_invoice i on i.id = o.invoice" {
+		t.Errorf("expected full join to be left alone, got '%s'", sql)
+	}
+
+	TenantScopeDebug = true
+	defer func() {
+		TenantScopeDebug = false
+	}()
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected full join on a tenant table to panic with TenantScopeDebug")
+			}
+		}()
+
+		ScopeSelect(ctx, query)
+	}()
+}
+
+func TestCheckTenantScope(t *testing.T) {
+	RegisterTenantTable("tenant_test_account", "tenant_id")
+
+	TenantScopeDebug = true
+	defer func() {
+		TenantScopeDebug = false
+	}()
+
+	ctx := context.Background()
+
+	panics := func(query string) (didPanic bool) {
+		defer func() {
+			didPanic = recover() != nil
+		}()
+
+		checkTenantScope(ctx, query)
+		return false
+	}
+
+	if !panics(`select * from tenant_test_account where id = $1`) {
+		t.Error("expected unfiltered query to panic")
+	}
+
+	if panics(`select * from tenant_test_account a where a.tenant_id = $1`) {
+		t.Error("expected filtered query to pass")
+	}
+
+	if panics(`select * from tenant_test_account_log where id = $1`) {
+		t.Error("expected unregistered table to pass")
+	}
+
+	if !panics(`select * from tenant_test_account a join tenant_test_account_log l on l.account = a.id where l.tenant_id = $1`) {
+		t.Error("expected filter on the joined table to panic")
+	}
+
+	if !panics(`select * from tenant_test_account where tenant_id = $1`) {
+		t.Error("expected unqualified filter to panic")
+	}
+
+	if panics(`select * from tenant_test_account_log l join tenant_test_account as a on a.id = l.account where a.tenant_id = $1`) {
+		t.Error("expected filter on the joined alias to pass")
+	}
+
+	if panics(`update tenant_test_account set name = $1 where tenant_test_account.tenant_id = $2`) {
+		t.Error("expected filter on the table name to pass")
+	}
+
+	if panics(`insert into tenant_test_account (name) values ($1)`) {
+		t.Error("expected insert to pass")
+	}
+}
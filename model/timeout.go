@@ -0,0 +1,175 @@
+package model
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// TimeoutError wraps database errors caused by a timeout or cancellation so callers (and res.Error)
+// can tell them apart from other failures. The original error is available through errors.As/Unwrap.
+type TimeoutError struct {
+	Err error
+
+	// Status is the http status that best describes the timeout:
+	//   - 504 Gateway Timeout for statement timeouts and context deadlines (the query was too slow)
+	//   - 503 Service Unavailable for idle-in-transaction, lock timeouts and connection exhaustion (try again later)
+	Status int
+}
+
+func (e *TimeoutError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// HttpStatus implements er.ErrorWithHttpStatus
+func (e *TimeoutError) HttpStatus() int {
+	return e.Status
+}
+
+// IsTimeoutError reports whether err was caused by a statement/transaction timeout or a cancelled context
+func IsTimeoutError(err error) bool {
+	var target *TimeoutError
+	return errors.As(classifyError(err), &target)
+}
+
+// classifyError wraps timeout errors in *TimeoutError, other errors are returned unchanged
+// so existing comparisons (err == sql.ErrNoRows, err.(*pq.Error)) keep working.
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var existing *TimeoutError
+	if errors.As(err, &existing) {
+		return err
+	}
+
+	status := timeoutStatus(err)
+	if status == 0 {
+		return err
+	}
+
+	return &TimeoutError{Err: err, Status: status}
+}
+
+func timeoutStatus(err error) int {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return http.StatusServiceUnavailable
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "57014": // query_canceled (statement_timeout)
+			return http.StatusGatewayTimeout
+		case "25P03", // idle_in_transaction_session_timeout
+			"55P03", // lock_not_available (lock_timeout)
+			"53300": // too_many_connections
+			return http.StatusServiceUnavailable
+		}
+
+		return 0
+	}
+
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		switch myErr.Number {
+		case 3024, // ER_QUERY_TIMEOUT (max_execution_time)
+			1969: // mariadb ER_STATEMENT_TIMEOUT (max_statement_time)
+			return http.StatusGatewayTimeout
+		case 1205, // ER_LOCK_WAIT_TIMEOUT
+			1040: // ER_CON_COUNT_ERROR
+			return http.StatusServiceUnavailable
+		}
+	}
+
+	return 0
+}
+
+func hasSessionTimeouts(opts *BeginTx2Options) bool {
+	return opts.StatementTimeout > 0 || opts.IdleInTransactionTimeout > 0
+}
+
+// applySessionTimeouts sets the timeouts for the transaction. Postgres uses SET LOCAL so the settings
+// end with the transaction. MySQL has no transaction-scoped equivalent, so the session variables are
+// set on a dedicated connection and reset by resetSessionTimeouts before it's returned to the pool.
+func applySessionTimeouts(ctx context.Context, tx *sqlx.Tx, opts *BeginTx2Options) error {
+	if defaultDbType == "mysql" {
+		if opts.StatementTimeout > 0 {
+			if _, err := tx.ExecContext(ctx, `set session max_execution_time = `+strconv.FormatInt(opts.StatementTimeout.Milliseconds(), 10)); err != nil {
+				return err
+			}
+		}
+
+		if opts.IdleInTransactionTimeout > 0 {
+			if _, err := tx.ExecContext(ctx, `set session wait_timeout = `+strconv.FormatInt(secondsCeil(opts.IdleInTransactionTimeout), 10)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if opts.StatementTimeout > 0 {
+		if _, err := tx.ExecContext(ctx, `set local statement_timeout = `+strconv.FormatInt(opts.StatementTimeout.Milliseconds(), 10)); err != nil {
+			return err
+		}
+	}
+
+	if opts.IdleInTransactionTimeout > 0 {
+		if _, err := tx.ExecContext(ctx, `set local idle_in_transaction_session_timeout = `+strconv.FormatInt(opts.IdleInTransactionTimeout.Milliseconds(), 10)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resetSessionTimeouts undoes the mysql session variables and releases the dedicated connection
+func resetSessionTimeouts(info *txInfo) {
+	if info.conn == nil {
+		return
+	}
+
+	// the request context may already be cancelled, the reset has to run regardless
+	_, err := info.conn.ExecContext(context.Background(), `set session max_execution_time = default, wait_timeout = default`)
+	if err != nil {
+		verboseError(err)
+
+		// don't return a connection with unknown session settings to the pool
+		_ = info.conn.Raw(func(driverConn interface{}) error {
+			return driver.ErrBadConn
+		})
+	}
+
+	_ = info.conn.Close()
+	info.conn = nil
+}
+
+func secondsCeil(d time.Duration) int64 {
+	s := int64(d / time.Second)
+	if d%time.Second != 0 {
+		s++
+	}
+
+	if s < 1 {
+		s = 1
+	}
+
+	return s
+}
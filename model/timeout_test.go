@@ -0,0 +1,52 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/ntbosscher/gobase/er"
+)
+
+func TestClassifyError(t *testing.T) {
+
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{err: &pq.Error{Code: "57014"}, status: 504},
+		{err: &pq.Error{Code: "25P03"}, status: 503},
+		{err: &pq.Error{Code: "23505"}, status: 0},
+		{err: &mysql.MySQLError{Number: 3024}, status: 504},
+		{err: fmt.Errorf("query: %w", context.DeadlineExceeded), status: 504},
+		{err: sql.ErrNoRows, status: 0},
+	}
+
+	for _, test := range tests {
+		err := classifyError(test.err)
+
+		if test.status == 0 {
+			if err != test.err {
+				t.Errorf("expected %v to be returned unchanged", test.err)
+			}
+
+			continue
+		}
+
+		if !IsTimeoutError(err) {
+			t.Errorf("expected %v to be a timeout", test.err)
+		}
+
+		if !errors.Is(err, test.err) {
+			t.Errorf("expected %v to wrap the original error", test.err)
+		}
+
+		if got := er.HttpStatus(err, 500); got != test.status {
+			t.Errorf("incorrect status for %v, expected %d got %d", test.err, test.status, got)
+		}
+	}
+}
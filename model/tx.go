@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -13,6 +14,7 @@ type txInfo struct {
 	rollbackCalled               bool
 	traceId                      string
 	tx                           *sqlx.Tx
+	conn                         *sqlx.Conn // set when the tx needs a dedicated connection (see resetSessionTimeouts)
 	commitCallbacks              []func()
 	rollbackOrCommitErrCallbacks []func()
 	lastError                    error
@@ -22,11 +24,28 @@ type BeginTx2Options struct {
 	TraceID        string
 	IsolationLevel sql.IsolationLevel
 	Readonly       bool
+
+	// StatementTimeout aborts any single statement that runs longer than this.
+	// Applied with "set local statement_timeout" on postgres and "max_execution_time" on mysql (selects only).
+	// default: 0 (database default)
+	StatementTimeout time.Duration
+
+	// IdleInTransactionTimeout closes the transaction if it sits idle (no statement running) longer than this.
+	// Applied with "set local idle_in_transaction_session_timeout" on postgres and "wait_timeout" on mysql.
+	// default: 0 (database default)
+	IdleInTransactionTimeout time.Duration
 }
 
+// BeginTx2 starts a transaction and attaches it to the returned context.
+// Cancelling ctx aborts the running statement and rolls back the transaction, timeouts and
+// cancellations are reported as *TimeoutError (see IsTimeoutError).
 func BeginTx2(ctx context.Context, opts *BeginTx2Options) (context.Context, func(), error) {
-	tx, err := startTx(ctx, &sql.TxOptions{Isolation: opts.IsolationLevel, ReadOnly: opts.Readonly})
+	after := txHook("Begin")
+
+	tx, conn, err := startTx2(ctx, &sql.TxOptions{Isolation: opts.IsolationLevel, ReadOnly: opts.Readonly}, opts)
 	if err != nil {
+		err = classifyError(err)
+		after(ctx, err)
 		return nil, nil, err
 	}
 
@@ -36,11 +55,14 @@ func BeginTx2(ctx context.Context, opts *BeginTx2Options) (context.Context, func
 		commitCalled:                 false,
 		rollbackCalled:               false,
 		tx:                           tx,
+		conn:                         conn,
 		traceId:                      opts.TraceID,
 		commitCallbacks:              []func(){},
 		rollbackOrCommitErrCallbacks: []func(){},
 	})
 
+	after(ctx, nil)
+
 	cleanup := func() {
 
 		info := getInfo(ctx)
@@ -100,7 +122,10 @@ func Rollback(ctx context.Context) error {
 	info.commitCalled = true
 	info.rollbackCalled = true
 	debugLogger().Println("rollback", info.traceId)
+	after := txHook("Rollback")
 	err := info.tx.Rollback()
+	resetSessionTimeouts(info)
+	after(ctx, err)
 
 	for _, callback := range info.rollbackOrCommitErrCallbacks {
 		callback()
@@ -119,7 +144,11 @@ func Commit(ctx context.Context) error {
 
 	info.commitCalled = true
 	debugLogger().Println("commit", info.traceId)
-	err := info.tx.Commit()
+	after := txHook("Commit")
+	err := classifyError(info.tx.Commit())
+	resetSessionTimeouts(info)
+	after(ctx, err)
+
 	if err != nil {
 		for _, callback := range info.rollbackOrCommitErrCallbacks {
 			callback()
@@ -144,6 +173,47 @@ func startTx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, error) {
 	return tx, nil
 }
 
+func startTx2(ctx context.Context, opts *sql.TxOptions, beginOpts *BeginTx2Options) (*sqlx.Tx, *sqlx.Conn, error) {
+	if !hasSessionTimeouts(beginOpts) {
+		tx, err := startTx(ctx, opts)
+		return tx, nil, err
+	}
+
+	// mysql session variables outlive the transaction, so pin a connection that can be reset afterwards
+	var conn *sqlx.Conn
+	var tx *sqlx.Tx
+	var err error
+
+	if defaultDbType == "mysql" {
+		conn, err = getDb(ctx).Connx(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		tx, err = conn.BeginTxx(ctx, opts)
+		if err != nil {
+			_ = conn.Close()
+			return nil, nil, err
+		}
+	} else {
+		tx, err = startTx(ctx, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := applySessionTimeouts(ctx, tx, beginOpts); err != nil {
+		_ = tx.Rollback()
+		if conn != nil {
+			_ = conn.Close()
+		}
+
+		return nil, nil, err
+	}
+
+	return tx, conn, nil
+}
+
 // WithTx runs the callback in a sql transaction. If the callback inTx
 // returns an error, the transaction is rolled back
 func WithTx(ctx context.Context, inTx func(ctx context.Context, tx *sqlx.Tx) error) error {
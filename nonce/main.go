@@ -62,6 +62,8 @@ func (n *nonce) cleaner() {
 
 }
 
+// New rejects reused X-Nonce values with a 400. Nonces are only kept in memory (for this instance),
+// see model.IdempotencyHandler to replay responses across instances instead.
 func New() func(next http.Handler) http.Handler {
 	n := &nonce{
 		used: map[string]time.Time{},
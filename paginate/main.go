@@ -13,8 +13,10 @@ import (
 	"github.com/ntbosscher/gobase/model"
 	"github.com/ntbosscher/gobase/model/squtil"
 	"github.com/ntbosscher/gobase/res"
+	"math"
 	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 	"unicode"
@@ -70,6 +72,7 @@ func ParamsFromRequest(rq *res.Request) Config {
 		},
 		DownloadFileName(rq.Query("download")), // download triggers a csv file download
 		decodeRequestFilter(rq.Query("filter")),
+		decodeRequestBoundingBox(rq.Query("bbox")),
 	}
 }
 
@@ -105,8 +108,43 @@ func decodeRequestFilter(value string) []Filter {
 	return out
 }
 
+// decodeRequestBoundingBox parses bbox=minLng,minLat,maxLng,maxLat (the GeoJSON bbox order)
+func decodeRequestBoundingBox(value string) Config {
+	if value == "" {
+		return nil
+	}
+
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		er.Throw("invalid bbox '" + value + "', expected minLng,minLat,maxLng,maxLat")
+	}
+
+	var values [4]float64
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			er.Throw("invalid bbox '" + value + "', expected minLng,minLat,maxLng,maxLat")
+		}
+
+		values[i] = v
+	}
+
+	if values[0] > values[2] || values[1] > values[3] {
+		er.Throw("invalid bbox '" + value + "', min must be less than max")
+	}
+
+	return model.BoundingBox{MinX: values[0], MinY: values[1], MaxX: values[2], MaxY: values[3], SRID: model.SRIDWGS84}
+}
+
 type DownloadFileName string
 
+// BoundingBoxColumn is the geometry column a model.BoundingBox (e.g. from the request's bbox param)
+// filters on. Without it, the bounding box is ignored.
+type BoundingBoxColumn string
+
+// IncludeDeleted includes soft-deleted rows (see model.RegisterSoftDeleteTable)
+type IncludeDeleted bool
+
 type queryConfig struct {
 	columnMapping    ColumnMapping
 	searchFields     []string
@@ -117,6 +155,9 @@ type queryConfig struct {
 	downloadFileName string
 	filter           []Filter
 	resultProcessor  resultProcessor
+	includeDeleted   bool
+	boundingBox      *model.BoundingBox
+	boundingBoxCol   string
 }
 
 func (q *queryConfig) decodeConfig(configList []Config) {
@@ -137,6 +178,12 @@ func (q *queryConfig) decodeConfig(configList []Config) {
 			q.resultProcessor = v
 		case DownloadFileName:
 			q.downloadFileName = string(v)
+		case IncludeDeleted:
+			q.includeDeleted = bool(v)
+		case model.BoundingBox:
+			q.boundingBox = &v
+		case BoundingBoxColumn:
+			q.boundingBoxCol = string(v)
 		case Filter:
 			q.filter = append(q.filter, v)
 		case []Filter:
@@ -205,6 +252,14 @@ func applyFilters(query squirrel.SelectBuilder, cfg *queryConfig, listDest inter
 	return query
 }
 
+func applyBoundingBox(query squirrel.SelectBuilder, cfg *queryConfig) squirrel.SelectBuilder {
+	if cfg.boundingBox == nil || cfg.boundingBoxCol == "" {
+		return query
+	}
+
+	return query.Where(squtil.InBoundingBox(cfg.boundingBoxCol, *cfg.boundingBox))
+}
+
 // Query applies the configuration options to the query and returns a paginated response
 // listDest should contain the type of result to expect (e.g. &[]*Person{})
 func Query(ctx context.Context, listDest interface{}, baseQuery squirrel.SelectBuilder, config ...Config) res.Responder {
@@ -214,19 +269,25 @@ func Query(ctx context.Context, listDest interface{}, baseQuery squirrel.SelectB
 	cfg := &queryConfig{}
 	cfg.decodeConfig(config)
 
+	if cfg.includeDeleted {
+		ctx = model.IncludeDeleted(ctx)
+	}
+
 	downloadFileName := cfg.downloadFileName
 	isDownload := downloadFileName != ""
 
 	query = applySearch(query, cfg)
 	query = applyFilters(query, cfg, listDest)
+	query = applyBoundingBox(query, cfg)
 
 	totalCount := 0
 
 	// don't need totalCount for downloads
 	if !isDownload {
+		// squtil scopes the list query (tenant, soft-delete), the count has to be scoped explicitly
 		err := model.Builder.
 			Select("count(*)").
-			FromSelect(query, "d").
+			FromSelect(model.ScopeSelect(ctx, query), "d").
 			RunWith(model.Tx(ctx)).
 			Scan(&totalCount)
 
@@ -437,3 +498,18 @@ func (date *Date) Scan(value interface{}) error {
 func (date Date) Value() (driver.Value, error) {
 	return time.Time(date), nil
 }
+
+// DateFromModel converts a model.Date (e.g. from model.UserDate) to a Date
+func DateFromModel(d model.Date) Date {
+	return Date(time.Time(d))
+}
+
+// ToModel returns the calendar date as a model.Date
+func (date Date) ToModel() model.Date {
+	return model.DateIn(time.Time(date), time.UTC)
+}
+
+// UserDate returns the user's local calendar date (see model.UserDate)
+func (date Date) UserDate(ctx context.Context) model.Date {
+	return model.UserDate(ctx, time.Time(date))
+}
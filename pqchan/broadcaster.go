@@ -0,0 +1,20 @@
+package pqchan
+
+import (
+	"context"
+	"encoding/json"
+)
+
+type broadcaster struct{}
+
+func (broadcaster) Send(ctx context.Context, name string, value interface{}) error {
+	return Send(ctx, name, value)
+}
+
+func (broadcaster) Receive(ctx context.Context, name string) (chan json.RawMessage, error) {
+	return Receive(ctx, name)
+}
+
+// Broadcaster exposes Send and Receive as a value for packages that accept a broadcaster
+// interface (e.g. modelutil.SetCacheBroadcaster)
+var Broadcaster = broadcaster{}
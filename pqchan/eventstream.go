@@ -0,0 +1,31 @@
+package pqchan
+
+import (
+	"context"
+
+	"github.com/ntbosscher/gobase/res"
+)
+
+// EventStream streams messages sent to the channel name to the client as server-sent events
+// (see res.EventStream). Messages sent while the client is disconnected aren't replayed.
+//
+//	rt.Get("/api/notifications", func(rq *res.Request) res.Responder {
+//		return pqchan.EventStream("notifications-"+strconv.Itoa(auth.User(rq.Context())), "notification")
+//	})
+func EventStream(name string, event string) res.Responder {
+	if !nameValidation.MatchString(name) {
+		return res.InternalServerError("invalid channel name, must match " + nameRegexpStr)
+	}
+
+	return res.EventStream(func(ctx context.Context, send res.EventSender) {
+		messages, err := Receive(ctx, name)
+		if err != nil {
+			Logger.Println(err)
+			return
+		}
+
+		if err := res.PipeEvents(ctx, send, event, messages); err != nil && err != res.ErrEventStreamClosed && ctx.Err() == nil {
+			Logger.Println(err)
+		}
+	})
+}
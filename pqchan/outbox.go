@@ -0,0 +1,16 @@
+package pqchan
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// OutboxHandler broadcasts each outbox entry on the channel name (see model.Outbox).
+// Use it with model.RegisterOutboxHandler:
+//
+//	model.RegisterOutboxHandler("order-updated", pqchan.OutboxHandler("order_updated"))
+func OutboxHandler(name string) func(ctx context.Context, payload json.RawMessage) error {
+	return func(ctx context.Context, payload json.RawMessage) error {
+		return Send(ctx, name, payload)
+	}
+}
@@ -0,0 +1,44 @@
+package pqshared
+
+import (
+	"context"
+	"time"
+)
+
+// PoolStats is the json-friendly version of pgxpool.Stat
+type PoolStats struct {
+	MaxConns             int32         `json:"maxConns"`
+	TotalConns           int32         `json:"totalConns"`
+	AcquiredConns        int32         `json:"acquiredConns"`
+	IdleConns            int32         `json:"idleConns"`
+	ConstructingConns    int32         `json:"constructingConns"`
+	AcquireCount         int64         `json:"acquireCount"`
+	AcquireDuration      time.Duration `json:"acquireDuration"`
+	EmptyAcquireCount    int64         `json:"emptyAcquireCount"`
+	CanceledAcquireCount int64         `json:"canceledAcquireCount"`
+}
+
+// Stats returns the current stats of Pool
+func Stats() *PoolStats {
+	s := Pool.Stat()
+
+	return &PoolStats{
+		MaxConns:             s.MaxConns(),
+		TotalConns:           s.TotalConns(),
+		AcquiredConns:        s.AcquiredConns(),
+		IdleConns:            s.IdleConns(),
+		ConstructingConns:    s.ConstructingConns(),
+		AcquireCount:         s.AcquireCount(),
+		AcquireDuration:      s.AcquireDuration(),
+		EmptyAcquireCount:    s.EmptyAcquireCount(),
+		CanceledAcquireCount: s.CanceledAcquireCount(),
+	}
+}
+
+// HealthCheck pings Pool and returns its stats. It matches model.HealthCheckFunc:
+//
+//	model.AddHealthCheck("pqshared", pqshared.HealthCheck)
+func HealthCheck(ctx context.Context) (interface{}, error) {
+	err := Pool.Ping(ctx)
+	return Stats(), err
+}
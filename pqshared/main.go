@@ -5,6 +5,7 @@ import (
 	"context"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/ntbosscher/gobase/env"
+	"github.com/ntbosscher/gobase/model"
 	"log"
 )
 
@@ -17,4 +18,8 @@ func init() {
 	if err != nil {
 		log.Fatal("failed to connect to postgres using environment variable CONNECTION_STRING", err)
 	}
+
+	model.AddPoolStats("pqshared", func() interface{} {
+		return Stats()
+	})
 }
@@ -0,0 +1,20 @@
+package pqworkqueue
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/ntbosscher/gobase/model"
+)
+
+// OutboxHandler adds each outbox entry to the queue as a job (see model.Outbox).
+// The job is inserted in the outbox dispatcher's transaction, so it's created exactly once per
+// delivered entry.
+//
+//	model.RegisterOutboxHandler("send-invoice", invoiceQueue.OutboxHandler())
+func (q *Queue) OutboxHandler() model.OutboxHandler {
+	return func(ctx context.Context, payload json.RawMessage) error {
+		_, err := q.Add(ctx, payload)
+		return err
+	}
+}
@@ -0,0 +1,298 @@
+package res
+
+import (
+	"bufio"
+	"context"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/ntbosscher/gobase/apiversion"
+	"github.com/ntbosscher/gobase/auth"
+	"github.com/ntbosscher/gobase/lg"
+	"github.com/ntbosscher/gobase/requestip"
+)
+
+// DefaultSensitiveQueryParams are never logged by AccessLog (matched case-insensitively)
+var DefaultSensitiveQueryParams = []string{
+	"password",
+	"token",
+	"access_token",
+	"refresh_token",
+	"id_token",
+	"secret",
+	"client_secret",
+	"key",
+	"api_key",
+	"apikey",
+	"code",
+	"signature",
+	"sig",
+	"auth",
+}
+
+type AccessLogConfig struct {
+	// SampleRate is the fraction (0-1) of successful requests that are logged, default 1 (all).
+	// Errors (status >= 400) and slow requests are always logged.
+	SampleRate float64
+
+	// SlowThreshold marks requests that take longer as slow (and always logs them). 0 disables it.
+	SlowThreshold time.Duration
+
+	// SensitiveQueryParams are replaced with [redacted], in addition to DefaultSensitiveQueryParams
+	SensitiveQueryParams []string
+
+	// Skip requests that shouldn't be logged (e.g. health checks)
+	Skip func(r *http.Request) bool
+
+	// Output writes the entry, default lg.Record(ctx, "http", entry.Fields())
+	Output func(ctx context.Context, entry *AccessLogEntry)
+}
+
+// AccessLogEntry is a single request logged by AccessLog
+type AccessLogEntry struct {
+	Method     string
+	Route      string // the route template, e.g. /api/person/{id:[0-9]+}
+	Path       string
+	Query      string // with sensitive values redacted
+	Status     int
+	Bytes      int64
+	Duration   time.Duration
+	IP         string
+	UserID     int
+	CompanyID  int
+	APIVersion string
+	TraceKey   string
+	Slow       bool
+}
+
+// Fields are the json fields logged for the entry
+func (e *AccessLogEntry) Fields() map[string]any {
+	fields := map[string]any{
+		"method":     e.Method,
+		"route":      e.Route,
+		"path":       e.Path,
+		"status":     e.Status,
+		"bytes":      e.Bytes,
+		"durationMs": float64(e.Duration.Microseconds()) / 1000,
+		"ip":         e.IP,
+		"traceKey":   e.TraceKey,
+	}
+
+	if e.Query != "" {
+		fields["query"] = e.Query
+	}
+
+	if e.UserID > 0 {
+		fields["userId"] = e.UserID
+	}
+
+	if e.CompanyID > 0 {
+		fields["companyId"] = e.CompanyID
+	}
+
+	if e.APIVersion != "" {
+		fields["apiVersion"] = e.APIVersion
+	}
+
+	if e.Slow {
+		fields["slow"] = true
+	}
+
+	return fields
+}
+
+// AccessLog is middleware that logs one json line per request through lg. Add it
+// with rt.Use (the auth user is picked up even if httpauth runs after it).
+//
+//	rt.Use(res.AccessLog(res.AccessLogConfig{
+//		SampleRate:           0.1,
+//		SlowThreshold:        time.Second,
+//		SensitiveQueryParams: []string{"ssn"},
+//	}))
+func AccessLog(config ...AccessLogConfig) mux.MiddlewareFunc {
+	cfg := AccessLogConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	if cfg.SampleRate <= 0 || cfg.SampleRate > 1 {
+		cfg.SampleRate = 1
+	}
+
+	if cfg.Output == nil {
+		cfg.Output = func(ctx context.Context, entry *AccessLogEntry) {
+			lg.Record(ctx, "http", entry.Fields())
+		}
+	}
+
+	sensitive := map[string]bool{}
+	for _, list := range [][]string{DefaultSensitiveQueryParams, cfg.SensitiveQueryParams} {
+		for _, name := range list {
+			sensitive[strings.ToLower(name)] = true
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if cfg.Skip != nil && cfg.Skip(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+
+			state := &accessLogState{}
+			ctx := lg.NewContext(r.Context(), lg.OptWithRequest(r))
+			ctx = context.WithValue(ctx, accessLogKey, state)
+			r = r.WithContext(ctx)
+
+			wr := &accessLogWriter{ResponseWriter: w}
+			next.ServeHTTP(wr, r)
+
+			entry := newAccessLogEntry(r, state, wr, time.Since(start), sensitive)
+			entry.Slow = cfg.SlowThreshold > 0 && entry.Duration >= cfg.SlowThreshold
+
+			if entry.Status < 400 && !entry.Slow && cfg.SampleRate < 1 && accessLogRandom() >= cfg.SampleRate {
+				return
+			}
+
+			cfg.Output(context.WithoutCancel(ctx), entry)
+		})
+	}
+}
+
+var accessLogRandom = rand.Float64
+
+type accessLogKeyType string
+
+const accessLogKey accessLogKeyType = "res-access-log"
+
+// accessLogState lets WrapHTTPFunc hand the request (with the auth user, api version...)
+// back to AccessLog when AccessLog runs before the middleware that sets them
+type accessLogState struct {
+	request *http.Request
+}
+
+func captureAccessLogRequest(r *http.Request) {
+	if state, ok := r.Context().Value(accessLogKey).(*accessLogState); ok {
+		state.request = r
+	}
+}
+
+func newAccessLogEntry(r *http.Request, state *accessLogState, wr *accessLogWriter, duration time.Duration, sensitive map[string]bool) *AccessLogEntry {
+	if state.request != nil {
+		r = state.request
+	}
+
+	ctx := r.Context()
+
+	entry := &AccessLogEntry{
+		Method:   r.Method,
+		Path:     r.URL.Path,
+		Query:    redactQuery(r.URL.RawQuery, sensitive),
+		Status:   wr.status,
+		Bytes:    wr.bytes,
+		Duration: duration,
+		IP:       requestip.KeyFromRequest(ctx, r.RemoteAddr),
+		TraceKey: lg.CurrentKey(ctx),
+	}
+
+	if entry.Status == 0 {
+		entry.Status = http.StatusOK
+	}
+
+	if route := mux.CurrentRoute(r); route != nil {
+		entry.Route, _ = route.GetPathTemplate()
+	}
+
+	if auth.IsAuthenticated(ctx) {
+		entry.UserID = auth.User(ctx)
+		entry.CompanyID = auth.Company(ctx)
+	}
+
+	if version := apiversion.Current(ctx); version != nil {
+		entry.APIVersion = version.String()
+	} else {
+		entry.APIVersion = apiversion.Parse(r)
+	}
+
+	return entry
+}
+
+func redactQuery(rawQuery string, sensitive map[string]bool) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "[unparseable]"
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	parts := []string{}
+	for _, key := range keys {
+		for _, value := range values[key] {
+			if sensitive[strings.ToLower(key)] {
+				value = "[redacted]"
+			}
+
+			parts = append(parts, url.QueryEscape(key)+"="+url.QueryEscape(value))
+		}
+	}
+
+	return strings.Join(parts, "&")
+}
+
+type accessLogWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *accessLogWriter) WriteHeader(status int) {
+	if w.status == 0 || w.status < 200 {
+		w.status = status
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *accessLogWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	n, err := w.ResponseWriter.Write(data)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *accessLogWriter) Flush() {
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *accessLogWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.status = http.StatusSwitchingProtocols
+	}
+
+	return conn, rw, err
+}
+
+func (w *accessLogWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,87 @@
+package res
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ntbosscher/gobase/auth"
+)
+
+func TestAccessLog(t *testing.T) {
+	var entries []*AccessLogEntry
+
+	defer func(random func() float64) {
+		accessLogRandom = random
+	}(accessLogRandom)
+
+	accessLogRandom = func() float64 {
+		return 0.5
+	}
+
+	rt := NewRouter()
+	rt.Use(AccessLog(AccessLogConfig{
+		SampleRate:           0.1,
+		SlowThreshold:        20 * time.Millisecond,
+		SensitiveQueryParams: []string{"ssn"},
+		Output: func(ctx context.Context, entry *AccessLogEntry) {
+			entries = append(entries, entry)
+		},
+	}))
+
+	// runs after AccessLog, like httpauth would
+	rt.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := auth.SetUser(r.Context(), &auth.UserInfo{UserID: 3, CompanyID: 4})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+
+	rt.Get("/api/person/{id:[0-9]+}", func(rq *Request) Responder {
+		if rq.Query("slow") != "" {
+			time.Sleep(25 * time.Millisecond)
+		}
+
+		if rq.Query("fail") != "" {
+			return BadRequest("nope")
+		}
+
+		return Ok("done")
+	})
+
+	get := func(path string) {
+		rq := httptest.NewRequest("GET", path, nil)
+		rq.Header.Set("X-APIVersion", "3")
+		rt.ServeHTTP(httptest.NewRecorder(), rq)
+	}
+
+	get("/api/person/1")
+	if len(entries) != 0 {
+		t.Fatal("expected the successful request to be sampled out")
+	}
+
+	get("/api/person/1?fail=1&token=abc&SSN=123&name=bob")
+	get("/api/person/2?slow=1")
+
+	if len(entries) != 2 {
+		t.Fatal("expected errors and slow requests to be logged, got", len(entries))
+	}
+
+	entry := entries[0]
+	if entry.Method != "GET" || entry.Route != "/api/person/{id:[0-9]+}" || entry.Path != "/api/person/1" ||
+		entry.Status != http.StatusBadRequest || entry.Bytes == 0 || entry.UserID != 3 || entry.CompanyID != 4 ||
+		entry.APIVersion != "3" || entry.IP != "192.0.2.1" || entry.TraceKey == "" || entry.Slow {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+
+	if entry.Query != "SSN=%5Bredacted%5D&fail=1&name=bob&token=%5Bredacted%5D" || strings.Contains(entry.Query, "abc") {
+		t.Fatal("unexpected query", entry.Query)
+	}
+
+	if !entries[1].Slow || entries[1].Status != http.StatusOK {
+		t.Fatalf("expected a slow entry, got %+v", entries[1])
+	}
+}
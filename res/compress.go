@@ -0,0 +1,466 @@
+package res
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gorilla/mux"
+)
+
+// DefaultCompressibleContentTypes is used when CompressionConfig.ContentTypes is empty.
+// Entries ending in "/" match a whole family (e.g. "text/"). text/event-stream is excluded
+// since it has to reach the client as soon as it's flushed.
+var DefaultCompressibleContentTypes = []string{
+	"text/html",
+	"text/css",
+	"text/plain",
+	"text/javascript",
+	"text/csv",
+	"text/xml",
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"application/manifest+json",
+	"application/wasm",
+	"image/svg+xml",
+}
+
+type CompressionConfig struct {
+	// MinSize is the smallest response (in bytes) worth compressing, default 1024
+	MinSize int
+
+	// ContentTypes that will be compressed, default DefaultCompressibleContentTypes
+	ContentTypes []string
+
+	// GzipLevel default gzip.DefaultCompression
+	GzipLevel int
+
+	// BrotliLevel default 4 (a good balance between speed and size for dynamic responses)
+	BrotliLevel int
+
+	// DisableBrotli only offers gzip
+	DisableBrotli bool
+}
+
+// Compress is middleware that gzip or brotli compresses responses when the client
+// supports it (Accept-Encoding). Only responses of at least MinSize bytes with an
+// allowed content-type are compressed.
+//
+// Websocket upgrades, flushed streams (e.g. EventStream) and responses that already have a
+// Content-Encoding (e.g. precompressed static files, see StaticFileDir) are passed through as-is.
+//
+//	rt.Use(res.Compress())
+func Compress(config ...CompressionConfig) mux.MiddlewareFunc {
+	cfg := CompressionConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	if cfg.MinSize <= 0 {
+		cfg.MinSize = 1024
+	}
+
+	if len(cfg.ContentTypes) == 0 {
+		cfg.ContentTypes = DefaultCompressibleContentTypes
+	}
+
+	if cfg.GzipLevel == 0 {
+		cfg.GzipLevel = gzip.DefaultCompression
+	}
+
+	if cfg.BrotliLevel == 0 {
+		cfg.BrotliLevel = 4
+	}
+
+	c := &compressor{config: cfg}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			addVary(w.Header(), "Accept-Encoding")
+
+			encoding := c.negotiate(r)
+			if encoding == "" || r.Method == http.MethodHead || r.Header.Get("Upgrade") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				compressor:     c,
+				encoding:       encoding,
+			}
+
+			defer cw.close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+type compressor struct {
+	config     CompressionConfig
+	gzipPool   sync.Pool
+	brotliPool sync.Pool
+}
+
+func (c *compressor) negotiate(r *http.Request) string {
+	header := r.Header.Get("Accept-Encoding")
+
+	if !c.config.DisableBrotli && acceptsEncoding(header, "br") {
+		return "br"
+	}
+
+	if acceptsEncoding(header, "gzip") {
+		return "gzip"
+	}
+
+	return ""
+}
+
+func (c *compressor) allowed(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, allowed := range c.config.ContentTypes {
+		if mediaType == allowed || (strings.HasSuffix(allowed, "/") && strings.HasPrefix(mediaType, allowed)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *compressor) encoder(encoding string, w io.Writer) io.WriteCloser {
+	if encoding == "br" {
+		if enc, ok := c.brotliPool.Get().(*brotli.Writer); ok {
+			enc.Reset(w)
+			return enc
+		}
+
+		return brotli.NewWriterLevel(w, c.config.BrotliLevel)
+	}
+
+	if enc, ok := c.gzipPool.Get().(*gzip.Writer); ok {
+		enc.Reset(w)
+		return enc
+	}
+
+	enc, err := gzip.NewWriterLevel(w, c.config.GzipLevel)
+	if err != nil {
+		enc = gzip.NewWriter(w)
+	}
+
+	return enc
+}
+
+func (c *compressor) release(enc io.WriteCloser) {
+	switch value := enc.(type) {
+	case *gzip.Writer:
+		c.gzipPool.Put(value)
+	case *brotli.Writer:
+		c.brotliPool.Put(value)
+	}
+}
+
+func addVary(header http.Header, value string) {
+	for _, existing := range header.Values("Vary") {
+		for _, item := range strings.Split(existing, ",") {
+			if strings.EqualFold(strings.TrimSpace(item), value) {
+				return
+			}
+		}
+	}
+
+	header.Add("Vary", value)
+}
+
+// acceptsEncoding checks an Accept-Encoding header for encoding, respecting q=0 and "*"
+func acceptsEncoding(header string, encoding string) bool {
+	wildcard := false
+
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		accepted := true
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			value, err := strconv.ParseFloat(q, 64)
+			accepted = err == nil && value > 0
+		}
+
+		switch name {
+		case encoding:
+			return accepted
+		case "*":
+			wildcard = accepted
+		}
+	}
+
+	return wildcard
+}
+
+// compressWriter buffers the start of the response until it knows whether it's worth compressing
+type compressWriter struct {
+	http.ResponseWriter
+	compressor *compressor
+	encoding   string
+
+	status  int
+	buffer  []byte
+	decided bool
+	enc     io.WriteCloser
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if w.decided {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	if w.status != 0 {
+		return // superfluous, the first status wins
+	}
+
+	if status < 200 {
+		// informational (e.g. 103 Early Hints), the real status comes later
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	w.status = status
+
+	if !bodyAllowedForStatus(status) || status == http.StatusPartialContent {
+		w.decide(false)
+	}
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	if !w.decided {
+		w.buffer = append(w.buffer, data...)
+
+		if len(w.buffer) < w.compressor.config.MinSize && !w.knownLarge() {
+			return len(data), nil
+		}
+
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+
+		return len(data), nil
+	}
+
+	if w.enc != nil {
+		return w.enc.Write(data)
+	}
+
+	return w.ResponseWriter.Write(data)
+}
+
+// knownLarge uses Content-Length (if the handler set one) to decide before MinSize is buffered
+func (w *compressWriter) knownLarge() bool {
+	length, err := strconv.Atoi(w.Header().Get("Content-Length"))
+	return err == nil && length >= w.compressor.config.MinSize
+}
+
+// decide sends the headers and whatever was buffered, with or without compression
+func (w *compressWriter) decide(bigEnough bool) error {
+	w.decided = true
+	header := w.Header()
+
+	if header.Get("Content-Type") == "" && len(w.buffer) > 0 {
+		header.Set("Content-Type", http.DetectContentType(w.buffer))
+	}
+
+	compress := bigEnough &&
+		header.Get("Content-Encoding") == "" &&
+		header.Get("Content-Range") == "" &&
+		w.compressor.allowed(header.Get("Content-Type"))
+
+	if compress {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		header.Del("Accept-Ranges")
+
+		// a strong etag identifies the uncompressed bytes
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+	}
+
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+
+	buffer := w.buffer
+	w.buffer = nil
+
+	if compress {
+		w.enc = w.compressor.encoder(w.encoding, w.ResponseWriter)
+		if len(buffer) > 0 {
+			_, err := w.enc.Write(buffer)
+			return err
+		}
+
+		return nil
+	}
+
+	if len(buffer) > 0 {
+		_, err := w.ResponseWriter.Write(buffer)
+		return err
+	}
+
+	return nil
+}
+
+// Flush sends everything written so far. Flushing before MinSize is reached means the
+// response is being streamed (e.g. EventStream), so it's sent uncompressed.
+func (w *compressWriter) Flush() {
+	w.FlushError()
+}
+
+func (w *compressWriter) FlushError() error {
+	if !w.decided {
+		if err := w.decide(false); err != nil {
+			return err
+		}
+	}
+
+	if flusher, ok := w.enc.(interface{ Flush() error }); ok {
+		if err := flusher.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *compressWriter) close() {
+	if !w.decided {
+		if w.status == 0 && len(w.buffer) == 0 {
+			// nothing was written, let net/http send its default response
+			return
+		}
+
+		w.decide(false)
+	}
+
+	if w.enc != nil {
+		w.enc.Close()
+		w.compressor.release(w.enc)
+		w.enc = nil
+	}
+}
+
+// precompressedFileServer is http.FileServer that serves foo.js.br or foo.js.gz (e.g.
+// from a react build with compression) in place of foo.js when the client supports it
+type precompressedFileServer struct {
+	root       http.FileSystem
+	fileServer http.Handler
+}
+
+func newPrecompressedFileServer(dir string) *precompressedFileServer {
+	return &precompressedFileServer{
+		root:       http.Dir(dir),
+		fileServer: http.FileServer(http.Dir(dir)),
+	}
+}
+
+var precompressedEncodings = []struct {
+	encoding  string
+	extension string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+func (s *precompressedFileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.servePrecompressed(w, r) {
+		return
+	}
+
+	s.fileServer.ServeHTTP(w, r)
+}
+
+func (s *precompressedFileServer) servePrecompressed(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	name := r.URL.Path
+	if !strings.HasPrefix(name, "/") {
+		name = "/" + name
+	}
+
+	name = path.Clean(name)
+	if strings.HasSuffix(r.URL.Path, "/") {
+		return false
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		return false
+	}
+
+	acceptEncoding := r.Header.Get("Accept-Encoding")
+
+	for _, item := range precompressedEncodings {
+		if !acceptsEncoding(acceptEncoding, item.encoding) {
+			continue
+		}
+
+		file, err := s.root.Open(name + item.extension)
+		if err != nil {
+			continue
+		}
+
+		stat, err := file.Stat()
+		if err != nil || stat.IsDir() {
+			file.Close()
+			continue
+		}
+
+		// only use the sibling if the original exists, so e.g. /foo.js.gz can't be requested as /foo.js
+		original, err := s.root.Open(name)
+		if err != nil {
+			file.Close()
+			return false
+		}
+
+		original.Close()
+		defer file.Close()
+
+		header := w.Header()
+		header.Set("Content-Type", contentType)
+		header.Set("Content-Encoding", item.encoding)
+		addVary(header, "Accept-Encoding")
+
+		http.ServeContent(w, r, name, stat.ModTime(), file)
+		return true
+	}
+
+	return false
+}
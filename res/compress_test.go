@@ -0,0 +1,112 @@
+package res
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat("a", 2000)
+
+	rt := NewRouter()
+	rt.Use(Compress())
+	rt.Get("/large", func(rq *Request) Responder {
+		return Ok(large)
+	})
+	rt.Get("/small", func(rq *Request) Responder {
+		return Ok("small")
+	})
+	rt.Get("/events", func(rq *Request) Responder {
+		return EventStream(func(ctx context.Context, send EventSender) {
+			send("", "hello")
+		})
+	})
+
+	get := func(path string, acceptEncoding string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		rq := httptest.NewRequest("GET", path, nil)
+		rq.Header.Set("Accept-Encoding", acceptEncoding)
+		rt.ServeHTTP(w, rq)
+		return w
+	}
+
+	w := get("/large", "gzip, deflate")
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatal("expected gzip, got", w.Header())
+	}
+
+	rd, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := io.ReadAll(rd)
+	if !strings.Contains(string(body), large) {
+		t.Fatal("unexpected body", string(body))
+	}
+
+	w = get("/large", "gzip;q=0.5, br")
+	if w.Header().Get("Content-Encoding") != "br" {
+		t.Fatal("expected br, got", w.Header())
+	}
+
+	body, _ = io.ReadAll(brotli.NewReader(w.Body))
+	if !strings.Contains(string(body), large) {
+		t.Fatal("unexpected body", string(body))
+	}
+
+	w = get("/large", "br;q=0, identity")
+	if w.Header().Get("Content-Encoding") != "" || !strings.Contains(w.Body.String(), large) {
+		t.Fatal("expected no compression, got", w.Header())
+	}
+
+	w = get("/small", "gzip")
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != "\"small\"\n" {
+		t.Fatal("expected no compression, got", w.Header(), w.Body.String())
+	}
+
+	w = get("/events", "gzip")
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != "data: hello\n\n" || !w.Flushed {
+		t.Fatal("expected an uncompressed stream, got", w.Header(), w.Body.String())
+	}
+}
+
+func TestStaticFileDirPrecompressed(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "app.js"), []byte("console.log(1)"), 0644)
+	os.WriteFile(filepath.Join(dir, "app.js.gz"), []byte("gzipped"), 0644)
+
+	rt := NewRouter()
+	rt.StaticFileDir("/static/", dir)
+
+	get := func(path string, acceptEncoding string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		rq := httptest.NewRequest("GET", path, nil)
+		rq.Header.Set("Accept-Encoding", acceptEncoding)
+		rt.ServeHTTP(w, rq)
+		return w
+	}
+
+	w := get("/static/app.js", "gzip, br")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Encoding") != "gzip" || w.Body.String() != "gzipped" {
+		t.Fatal("expected the .gz sibling, got", w.Code, w.Header(), w.Body.String())
+	}
+
+	if !strings.Contains(w.Header().Get("Content-Type"), "javascript") {
+		t.Fatal("expected the original's content-type, got", w.Header().Get("Content-Type"))
+	}
+
+	w = get("/static/app.js", "")
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != "console.log(1)" {
+		t.Fatal("expected the original, got", w.Header(), w.Body.String())
+	}
+}
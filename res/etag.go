@@ -0,0 +1,190 @@
+package res
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/ntbosscher/gobase/er"
+)
+
+// Conditional adds conditional GET support to Ok and List responses (other responses are
+// sent as-is). The json body is hashed into a strong ETag, and GET/HEAD requests with a
+// matching If-None-Match, or an If-Modified-Since that's not before the Last-Modified
+// set with WithLastModified, get a 304 Not Modified without the body.
+//
+// Use r.ETag() to enable it for a route. It works behind NoCache, see NoCache for how the two interact.
+func Conditional(resp Responder) Responder {
+	return &conditionalResponder{next: resp}
+}
+
+// WithLastModified sets the Last-Modified header of resp. Combined with Conditional
+// (r.ETag()), If-Modified-Since requests get a 304 when nothing changed.
+func WithLastModified(lastModified time.Time, resp Responder) Responder {
+	return &lastModifiedResponder{lastModified: lastModified, next: resp}
+}
+
+// ETagOf returns the ETag Conditional uses for an Ok/List response of value
+func ETagOf(value interface{}) string {
+	body, err := encodeBody(fixNilList(value))
+	er.Check(err)
+
+	return etagForBody(body)
+}
+
+// CheckIfMatch is used by PUT handlers to prevent lost updates: when the request has an
+// If-Match header that doesn't match the ETag of the current record (as it's returned by GET),
+// it throws a 412 Precondition Failed. current should be nil when the record doesn't exist.
+//
+//	person := loadPerson(ctx, id)
+//	res.CheckIfMatch(rq, person)
+//	savePerson(ctx, input)
+//
+// W/ prefixes (e.g. added by Compress) are ignored.
+func CheckIfMatch(rq *Request, current interface{}) {
+	header := conditionalHeader(rq.Request(), "If-Match")
+	if header == "" {
+		return
+	}
+
+	if isNil(current) {
+		er.ThrowClientSafeCode(http.StatusPreconditionFailed, "this record no longer exists")
+	}
+
+	if strings.TrimSpace(header) == "*" || etagListMatches(header, ETagOf(current)) {
+		return
+	}
+
+	er.ThrowClientSafeCode(http.StatusPreconditionFailed, "this record was changed by someone else, reload and try again")
+}
+
+func isNil(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Interface:
+		return v.IsNil()
+	default:
+		return false
+	}
+}
+
+type lastModifiedResponder struct {
+	lastModified time.Time
+	next         Responder
+}
+
+func (l *lastModifiedResponder) Respond(w http.ResponseWriter, r *http.Request) {
+	setLastModified(w, l.lastModified)
+	l.next.Respond(w, r)
+}
+
+func setLastModified(w http.ResponseWriter, lastModified time.Time) {
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+}
+
+type conditionalResponder struct {
+	next Responder
+}
+
+func (c *conditionalResponder) Respond(w http.ResponseWriter, r *http.Request) {
+	resp := c.next
+	var lastModified time.Time
+
+	if value, ok := resp.(*lastModifiedResponder); ok {
+		lastModified = value.lastModified
+		resp = value.next
+	}
+
+	value, ok := resp.(*responder)
+	if !ok || value.status != http.StatusOK {
+		setLastModified(w, lastModified)
+		resp.Respond(w, r)
+		return
+	}
+
+	body, err := encodeBody(value.data)
+	if err != nil {
+		resp.Respond(w, r) // let the responder deal with it
+		return
+	}
+
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		etag = etagForBody(body)
+		w.Header().Set("ETag", etag)
+	}
+
+	setLastModified(w, lastModified)
+
+	// NoCache's no-store would stop the browser keeping the response to revalidate
+	if strings.Contains(w.Header().Get("Cache-Control"), "no-store") {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+
+	if (r.Method == http.MethodGet || r.Method == http.MethodHead) && notModified(r, etag, lastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if r.Method != http.MethodHead {
+		w.Write(body)
+	}
+}
+
+// notModified follows RFC 9110 13.2.2: If-Modified-Since is ignored when If-None-Match is present
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if header := conditionalHeader(r, "If-None-Match"); header != "" {
+		return strings.TrimSpace(header) == "*" || etagListMatches(header, etag)
+	}
+
+	if lastModified.IsZero() {
+		return false
+	}
+
+	since, err := http.ParseTime(conditionalHeader(r, "If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// encodeBody matches what responder.Respond writes
+func encodeBody(data interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := json.NewEncoder(buf).Encode(data); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func etagForBody(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+func etagListMatches(header string, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+
+	for _, item := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(item), "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
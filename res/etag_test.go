@@ -0,0 +1,132 @@
+package res
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type etagPerson struct {
+	ID   int
+	Name string
+}
+
+func TestConditional(t *testing.T) {
+	person := &etagPerson{ID: 1, Name: "bob"}
+	modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	rt := NewRouter()
+	rt.Get("/person", func(rq *Request) Responder {
+		return Conditional(WithLastModified(modified, Ok(person)))
+	})
+	rt.Put("/person", func(rq *Request) Responder {
+		CheckIfMatch(rq, person)
+		return Conditional(Ok(person))
+	})
+
+	do := func(method string, header string, value string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		rq := httptest.NewRequest(method, "/person", strings.NewReader("{}"))
+		if header != "" {
+			rq.Header.Set(header, value)
+		}
+
+		rt.ServeHTTP(w, rq)
+		return w
+	}
+
+	w := do("GET", "", "")
+	etag := w.Header().Get("ETag")
+
+	if w.Code != http.StatusOK || etag != ETagOf(person) || !strings.Contains(w.Body.String(), `"name":"bob"`) {
+		t.Fatal("unexpected response", w.Code, w.Header(), w.Body.String())
+	}
+
+	if w.Header().Get("Last-Modified") != "Tue, 02 Jan 2024 03:04:05 GMT" {
+		t.Fatal("unexpected last-modified", w.Header().Get("Last-Modified"))
+	}
+
+	if w = do("GET", "If-None-Match", "W/"+etag); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Fatal("expected 304, got", w.Code, w.Body.String())
+	}
+
+	if w = do("GET", "If-None-Match", `"other"`); w.Code != http.StatusOK {
+		t.Fatal("expected 200, got", w.Code)
+	}
+
+	if w = do("GET", "If-Modified-Since", "Tue, 02 Jan 2024 03:04:05 GMT"); w.Code != http.StatusNotModified {
+		t.Fatal("expected 304, got", w.Code)
+	}
+
+	if w = do("GET", "If-Modified-Since", "Tue, 02 Jan 2024 03:04:04 GMT"); w.Code != http.StatusOK {
+		t.Fatal("expected 200, got", w.Code)
+	}
+
+	if w = do("PUT", "If-Match", etag); w.Code != http.StatusOK {
+		t.Fatal("expected 200, got", w.Code)
+	}
+
+	if w = do("PUT", "If-Match", `"stale"`); w.Code != http.StatusPreconditionFailed || !strings.Contains(w.Body.String(), "changed by someone else") {
+		t.Fatal("expected 412, got", w.Code, w.Body.String())
+	}
+
+	if w = do("PUT", "", ""); w.Code != http.StatusOK {
+		t.Fatal("expected 200 without If-Match, got", w.Code)
+	}
+}
+
+func TestConditionalPassesErrorsThrough(t *testing.T) {
+	w := httptest.NewRecorder()
+	Conditional(BadRequest("nope")).Respond(w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Code != http.StatusBadRequest || w.Header().Get("ETag") != "" {
+		t.Fatal("unexpected response", w.Code, w.Header())
+	}
+}
+
+func TestConditionalWithNoCache(t *testing.T) {
+	person := &etagPerson{ID: 1, Name: "bob"}
+
+	rt := NewRouter()
+	rt.Use(NoCache)
+	rt.Get("/person", func(rq *Request) Responder {
+		return Conditional(Ok(person))
+	})
+	rt.Put("/person", func(rq *Request) Responder {
+		CheckIfMatch(rq, person)
+		return Ok(person)
+	})
+	rt.Get("/other", func(rq *Request) Responder {
+		return Ok(person)
+	})
+
+	do := func(method string, path string, header string, value string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		rq := httptest.NewRequest(method, path, strings.NewReader("{}"))
+		if header != "" {
+			rq.Header.Set(header, value)
+		}
+
+		rt.ServeHTTP(w, rq)
+		return w
+	}
+
+	w := do("GET", "/person", "", "")
+	if w.Code != http.StatusOK || w.Header().Get("Cache-Control") != "no-cache" {
+		t.Fatal("expected a revalidating Cache-Control", w.Code, w.Header().Get("Cache-Control"))
+	}
+
+	if w = do("GET", "/person", "If-None-Match", ETagOf(person)); w.Code != http.StatusNotModified {
+		t.Fatal("expected 304 behind NoCache, got", w.Code)
+	}
+
+	if w = do("PUT", "/person", "If-Match", `"stale"`); w.Code != http.StatusPreconditionFailed {
+		t.Fatal("expected 412 behind NoCache, got", w.Code)
+	}
+
+	if w = do("GET", "/other", "", ""); !strings.Contains(w.Header().Get("Cache-Control"), "no-store") {
+		t.Error("expected other routes to keep no-store, got", w.Header().Get("Cache-Control"))
+	}
+}
@@ -0,0 +1,249 @@
+package res
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	stdjson "encoding/json"
+)
+
+var (
+	// EventStreamKeepAlive is how often a comment line is sent on idle event streams so
+	// proxies and load balancers don't close the connection. 0 disables keep-alives.
+	EventStreamKeepAlive = 15 * time.Second
+
+	// MaxConcurrentEventStreams caps the number of simultaneously-open event streams
+	// across the process (see MaxConcurrentWebSockets). Once reached, further requests are
+	// rejected with 503 Service Unavailable. 0 disables the cap.
+	MaxConcurrentEventStreams int64 = 1024
+)
+
+var openEventStreamCount int64
+
+// ErrEventStreamClosed is returned by send once the client has gone away
+var ErrEventStreamClosed = errors.New("event stream closed")
+
+// Event can be passed as the data of an EventSender to set the event id (sent back as
+// Last-Event-ID when the browser reconnects) or the client's reconnect delay
+type Event struct {
+	ID    string
+	Data  interface{}
+	Retry time.Duration
+}
+
+// EventSender sends an event to the client. An empty event name uses the default "message" event.
+// Data that's a string, []byte or json.RawMessage is sent as-is, anything else is json encoded.
+type EventSender func(event string, data interface{}) error
+
+// EventStreamHandler produces events until it returns or ctx is done (the client disconnected)
+type EventStreamHandler func(ctx context.Context, send EventSender)
+
+type lastEventIDKeyType string
+
+const lastEventIDKey lastEventIDKeyType = "res-last-event-id"
+
+// LastEventID is the id of the last event the client received before reconnecting
+// (the Last-Event-ID header), or "" for new connections. Use it to resume the stream.
+func LastEventID(ctx context.Context) string {
+	id, _ := ctx.Value(lastEventIDKey).(string)
+	return id
+}
+
+// EventStream responds with a Server-Sent Events (text/event-stream) stream. The response
+// is flushed after every event, keep-alive comments are sent every EventStreamKeepAlive and
+// the server's write timeout is lifted for the stream.
+//
+// Add the route to model.AttachTxHandler's ignore list so the stream doesn't hold a database
+// connection while the client is connected, and use model.WithTx for queries made while streaming.
+//
+//	rt.Use(model.AttachTxHandler("/api/jobs/{id}/progress"))
+//	rt.Get("/api/jobs/{id}/progress", func(rq *res.Request) res.Responder {
+//		return res.EventStream(func(ctx context.Context, send res.EventSender) {
+//			for progress := range watchJob(ctx, id, res.LastEventID(ctx)) {
+//				if err := send("progress", res.Event{ID: progress.ID, Data: progress}); err != nil {
+//					return
+//				}
+//			}
+//		})
+//	})
+func EventStream(handler EventStreamHandler) Responder {
+	return &freeformResponder{
+		respond: func(w http.ResponseWriter, r *http.Request) {
+			if !acquireConnectionSlot(&openEventStreamCount, MaxConcurrentEventStreams) {
+				http.Error(w, "too many event streams", http.StatusServiceUnavailable)
+				return
+			}
+
+			defer atomic.AddInt64(&openEventStreamCount, -1)
+
+			rc := http.NewResponseController(w)
+
+			// long-lived response: don't let http.Server.WriteTimeout cut it off
+			_ = rc.SetWriteDeadline(time.Time{})
+
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("X-Accel-Buffering", "no") // nginx
+			w.WriteHeader(http.StatusOK)
+
+			if err := rc.Flush(); err != nil {
+				logVerbose(0, errors.New("event stream: response writer can't flush: "+err.Error()))
+				return
+			}
+
+			stream := &eventStream{wr: w, rc: rc}
+
+			ctx := r.Context()
+			if id := r.Header.Get("Last-Event-ID"); id != "" {
+				ctx = context.WithValue(ctx, lastEventIDKey, id)
+			}
+
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+
+			if EventStreamKeepAlive > 0 {
+				go stream.keepAlive(ctx, EventStreamKeepAlive)
+			}
+
+			handler(ctx, stream.send)
+			stream.close()
+		},
+	}
+}
+
+type eventStream struct {
+	mu     sync.Mutex
+	wr     http.ResponseWriter
+	rc     *http.ResponseController
+	closed bool
+}
+
+func (s *eventStream) keepAlive(ctx context.Context, interval time.Duration) {
+	tc := time.NewTicker(interval)
+	defer tc.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tc.C:
+		}
+
+		if err := s.write(": keep-alive\n\n"); err != nil {
+			return
+		}
+	}
+}
+
+func (s *eventStream) send(event string, data interface{}) error {
+	if strings.ContainsAny(event, "\r\n") {
+		return errors.New("event stream: event name can't contain line breaks")
+	}
+
+	bld := strings.Builder{}
+
+	if value, ok := data.(*Event); ok && value != nil {
+		data = *value
+	}
+
+	if value, ok := data.(Event); ok {
+		if strings.ContainsAny(value.ID, "\r\n") {
+			return errors.New("event stream: event id can't contain line breaks")
+		}
+
+		if value.ID != "" {
+			bld.WriteString("id: " + value.ID + "\n")
+		}
+
+		if value.Retry > 0 {
+			bld.WriteString("retry: " + strconv.FormatInt(value.Retry.Milliseconds(), 10) + "\n")
+		}
+
+		data = value.Data
+	}
+
+	if event != "" {
+		bld.WriteString("event: " + event + "\n")
+	}
+
+	text, err := eventData(data)
+	if err != nil {
+		return err
+	}
+
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	for _, line := range strings.Split(text, "\n") {
+		bld.WriteString("data: " + line + "\n")
+	}
+
+	bld.WriteString("\n")
+	return s.write(bld.String())
+}
+
+func eventData(data interface{}) (string, error) {
+	switch value := data.(type) {
+	case string:
+		return value, nil
+	case []byte:
+		return string(value), nil
+	case stdjson.RawMessage:
+		return string(value), nil
+	default:
+		js, err := json.Marshal(value)
+		return string(js), err
+	}
+}
+
+func (s *eventStream) write(text string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrEventStreamClosed
+	}
+
+	if _, err := s.wr.Write([]byte(text)); err != nil {
+		s.closed = true
+		return ErrEventStreamClosed
+	}
+
+	if err := s.rc.Flush(); err != nil {
+		s.closed = true
+		return ErrEventStreamClosed
+	}
+
+	return nil
+}
+
+// close stops writes from goroutines that outlive the handler
+func (s *eventStream) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+}
+
+// PipeEvents sends every value from channel as event until the channel is closed,
+// ctx is done or the client goes away
+func PipeEvents[T any](ctx context.Context, send EventSender, event string, channel <-chan T) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case value, ok := <-channel:
+			if !ok {
+				return nil
+			}
+
+			if err := send(event, value); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package res
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventStream(t *testing.T) {
+	channel := make(chan map[string]int)
+	go func() {
+		channel <- map[string]int{"count": 1}
+		close(channel)
+	}()
+
+	rt := NewRouter()
+	rt.Get("/events", func(rq *Request) Responder {
+		return EventStream(func(ctx context.Context, send EventSender) {
+			send("resume", LastEventID(ctx))
+			send("", Event{ID: "2", Data: "line 1\nline 2"})
+			PipeEvents(ctx, send, "count", channel)
+		})
+	})
+
+	server := httptest.NewServer(rt)
+	defer server.Close()
+
+	rq, _ := http.NewRequest("GET", server.URL+"/events", nil)
+	rq.Header.Set("Last-Event-ID", "1")
+
+	resp, err := http.DefaultClient.Do(rq)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatal("unexpected content-type", resp.Header.Get("Content-Type"))
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	expected := "event: resume\ndata: 1\n\n" +
+		"id: 2\ndata: line 1\ndata: line 2\n\n" +
+		"event: count\ndata: {\"count\":1}\n\n"
+
+	if string(body) != expected {
+		t.Fatalf("unexpected body:\n%s", body)
+	}
+}
+
+func TestEventStreamLimits(t *testing.T) {
+	defer func(limit int64, keepAlive time.Duration) {
+		MaxConcurrentEventStreams = limit
+		EventStreamKeepAlive = keepAlive
+	}(MaxConcurrentEventStreams, EventStreamKeepAlive)
+
+	MaxConcurrentEventStreams = 1
+	EventStreamKeepAlive = 10 * time.Millisecond
+
+	rt := NewRouter()
+	rt.Get("/events", func(rq *Request) Responder {
+		return EventStream(func(ctx context.Context, send EventSender) {
+			<-ctx.Done()
+		})
+	})
+
+	server := httptest.NewServer(rt)
+	defer server.Close()
+
+	first, err := http.Get(server.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	line, _ := bufio.NewReader(first.Body).ReadString('\n')
+	if !strings.HasPrefix(line, ": keep-alive") {
+		t.Fatal("expected a keep-alive, got", line)
+	}
+
+	second, err := http.Get(server.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second.Body.Close()
+	first.Body.Close()
+
+	if second.StatusCode != http.StatusServiceUnavailable {
+		t.Fatal("expected 503, got", second.StatusCode)
+	}
+}
@@ -0,0 +1,71 @@
+package res
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+)
+
+// Exportable is a table that can be downloaded in several formats (e.g. modelutil.Table)
+type Exportable interface {
+	ToCSV() []byte
+	ToXLSX() ([]byte, error)
+	ToJSON() ([]byte, error)
+	ToMarkdown() []byte
+}
+
+// ExportFormatParam is the query parameter Export reads the format from
+var ExportFormatParam = "format"
+
+var exportContentTypes = map[string]string{
+	"csv":  "text/csv; charset=utf-8",
+	"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"json": "application/json",
+	"md":   "text/markdown; charset=utf-8",
+}
+
+// Export downloads table as name.<format> where format comes from the ExportFormatParam
+// query parameter: csv (default), xlsx, json or md.
+//
+//	tbl, err := modelutil.SelectTable(ctx, `select * from invoice where company = $1`, company)
+//	return res.Export("invoices", tbl)
+func Export(name string, table Exportable) Responder {
+	return &freeformResponder{
+		respond: func(w http.ResponseWriter, r *http.Request) {
+			format := strings.ToLower(r.URL.Query().Get(ExportFormatParam))
+			if format == "" {
+				format = "csv"
+			}
+
+			if format == "markdown" {
+				format = "md"
+			}
+
+			var data []byte
+			var err error
+
+			switch format {
+			case "csv":
+				data = table.ToCSV()
+			case "xlsx":
+				data, err = table.ToXLSX()
+			case "json":
+				data, err = table.ToJSON()
+			case "md":
+				data = table.ToMarkdown()
+			default:
+				BadRequest("invalid "+ExportFormatParam+", expected csv, xlsx, json or md").Respond(w, r)
+				return
+			}
+
+			if err != nil {
+				Error(err).Respond(w, r)
+				return
+			}
+
+			// set before Download so http.ServeContent doesn't sniff it
+			w.Header().Set("Content-Type", exportContentTypes[format])
+			Download(name+"."+format, bytes.NewReader(data)).Respond(w, r)
+		},
+	}
+}
@@ -0,0 +1,286 @@
+package res
+
+import (
+	"context"
+	"encoding"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/ntbosscher/gobase/er"
+)
+
+// Handle adapts a typed handler to a HandlerFunc2, so it works with Router.Route, r.Router.Add and
+// role middleware. In is bound from the request and validated (see Validate), Out is sent with Ok.
+//
+//	type GetPersonInput struct {
+//		ID      int  `path:"id"`
+//		Details bool `query:"details"`
+//	}
+//
+//	rt.Get("/api/person/{id:[0-9]+}", res.Handle(func(ctx context.Context, input GetPersonInput) (*Person, error) {
+//		return loadPerson(ctx, input.ID, input.Details)
+//	}))
+//
+// Binding:
+//   - the json body is decoded into In (for requests with a json, or missing, content-type)
+//   - then fields tagged `path:"name"`, `query:"name"`, `form:"name"` or `header:"name"` are set from
+//     the path variables, query string, form fields and headers
+//
+// A returned error is handled like er.Check: the status comes from er.ErrorWithHttpStatus, and the
+// message is only shown to the client for client-safe errors (er.ClientSafe). If Out is a Responder,
+// it's used as the response as-is.
+func Handle[In any, Out any](handler func(ctx context.Context, input In) (Out, error)) HandlerFunc2 {
+	return func(rq *Request) Responder {
+		var input In
+		checkParseError(Bind(rq, &input))
+
+		ctx := context.WithValue(rq.Context(), requestContextKey, rq)
+
+		out, err := handler(ctx, input)
+		er.Check(err)
+
+		if responder, ok := any(out).(Responder); ok {
+			return responder
+		}
+
+		return Ok(out)
+	}
+}
+
+type requestContextKeyType string
+
+const requestContextKey requestContextKeyType = "res-request"
+
+// CurrentRequest returns the request being handled by Handle (for cookies, the response writer...),
+// or nil outside of Handle
+func CurrentRequest(ctx context.Context) *Request {
+	rq, _ := ctx.Value(requestContextKey).(*Request)
+	return rq
+}
+
+// Bind fills dest (a pointer to a struct) from the request as described in Handle, then validates it.
+// Binding and validation failures are returned as ValidationErrors, json errors as they come from the decoder.
+func Bind(rq *Request, dest interface{}) error {
+	value := reflect.ValueOf(dest)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		panic("gobase/res: Bind expects a pointer")
+	}
+
+	// allow In to be a pointer type
+	for value.Elem().Kind() == reflect.Ptr {
+		if value.Elem().IsNil() {
+			value.Elem().Set(reflect.New(value.Elem().Type().Elem()))
+		}
+
+		value = value.Elem()
+	}
+
+	if hasJSONBody(rq.Request()) {
+		err := json.NewDecoder(rq.Request().Body).Decode(value.Interface())
+		if err != nil && err != io.EOF {
+			return err
+		}
+	}
+
+	if value.Elem().Kind() == reflect.Struct {
+		b := &binder{rq: rq}
+		b.fields(value.Elem())
+
+		if len(b.errors) > 0 {
+			return b.errors
+		}
+	}
+
+	return Validate(dest)
+}
+
+func hasJSONBody(req *http.Request) bool {
+	if req.Body == nil || req.Body == http.NoBody {
+		return false
+	}
+
+	contentType := req.Header.Get("Content-Type")
+	if contentType == "" {
+		return req.Method != http.MethodGet && req.Method != http.MethodHead
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+type binder struct {
+	rq     *Request
+	vars   map[string]string
+	errors ValidationErrors
+}
+
+func (b *binder) fields(value reflect.Value) {
+	t := value.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			b.fields(value.Field(i))
+			continue
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		if name := f.Tag.Get("path"); name != "" {
+			if b.vars == nil {
+				b.vars = mux.Vars(b.rq.Request())
+			}
+
+			if v, ok := b.vars[name]; ok {
+				b.set(name, value.Field(i), []string{v})
+			}
+		}
+
+		if name := f.Tag.Get("query"); name != "" {
+			if v, ok := b.rq.Request().URL.Query()[name]; ok {
+				b.set(name, value.Field(i), v)
+			}
+		}
+
+		if name := f.Tag.Get("form"); name != "" && b.rq.ensureFormParsed() {
+			if v, ok := b.rq.Request().PostForm[name]; ok {
+				b.set(name, value.Field(i), v)
+			}
+		}
+
+		if name := f.Tag.Get("header"); name != "" {
+			if v := b.rq.Request().Header.Values(name); len(v) > 0 {
+				b.set(name, value.Field(i), v)
+			}
+		}
+	}
+}
+
+func (b *binder) set(name string, field reflect.Value, values []string) {
+	if err := setFromStrings(field, values); err != nil {
+		b.errors = append(b.errors, &FieldError{Field: name, Rule: "type", Message: err.Error()})
+	}
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+var timeType = reflect.TypeOf(time.Time{})
+
+type bindError string
+
+func (b bindError) Error() string {
+	return string(b)
+}
+
+func setFromStrings(field reflect.Value, values []string) error {
+	if field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8 &&
+		!reflect.PointerTo(field.Type()).Implements(textUnmarshalerType) {
+
+		// ?id=1&id=2 or ?id=1,2
+		var parts []string
+		for _, v := range values {
+			parts = append(parts, strings.Split(v, ",")...)
+		}
+
+		list := reflect.MakeSlice(field.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := setFromString(list.Index(i), part); err != nil {
+				return err
+			}
+		}
+
+		field.Set(list)
+		return nil
+	}
+
+	return setFromString(field, values[0])
+}
+
+func setFromString(field reflect.Value, value string) error {
+	if field.Kind() == reflect.Ptr {
+		ptr := reflect.New(field.Type().Elem())
+		if err := setFromString(ptr.Elem(), value); err != nil {
+			return err
+		}
+
+		field.Set(ptr)
+		return nil
+	}
+
+	if field.Type() == timeType {
+		tm, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return bindError("must be a date/time (RFC3339)")
+		}
+
+		field.Set(reflect.ValueOf(tm))
+		return nil
+	}
+
+	if field.CanAddr() && field.Addr().Type().Implements(textUnmarshalerType) {
+		if err := field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value)); err != nil {
+			return bindError("is invalid")
+		}
+
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		if value == "" {
+			field.SetBool(true) // ?flag
+			return nil
+		}
+
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return bindError("must be true or false")
+		}
+
+		field.SetBool(v)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return bindError("must be a whole number")
+		}
+
+		field.SetInt(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return bindError("must be a positive whole number")
+		}
+
+		field.SetUint(v)
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return bindError("must be a number")
+		}
+
+		field.SetFloat(v)
+	default:
+		// e.g. nulls.Int, model.Date: decode as a json value
+		ptr := reflect.New(field.Type())
+		if err := json.Unmarshal([]byte(value), ptr.Interface()); err != nil {
+			js, _ := json.Marshal(value)
+			if err := json.Unmarshal(js, ptr.Interface()); err != nil {
+				return bindError("is invalid")
+			}
+		}
+
+		field.Set(ptr.Elem())
+	}
+
+	return nil
+}
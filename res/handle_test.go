@@ -0,0 +1,65 @@
+package res
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ntbosscher/gobase/er"
+)
+
+type handleInput struct {
+	ID      int      `path:"id"`
+	Page    int      `query:"page"`
+	Tags    []string `query:"tag"`
+	Name    string   `validate:"required"`
+	TraceID string   `header:"X-Trace"`
+}
+
+func TestHandle(t *testing.T) {
+	var got handleInput
+
+	rt := NewRouter()
+	rt.Route("POST", "/person/{id:[0-9]+}", Handle(func(ctx context.Context, input handleInput) (map[string]string, error) {
+		got = input
+
+		if CurrentRequest(ctx) == nil {
+			t.Error("expected the request on ctx")
+		}
+
+		if input.Name == "fail" {
+			return nil, er.ClientSafe("name can't be fail")
+		}
+
+		return map[string]string{"name": input.Name}, nil
+	}))
+
+	w := httptest.NewRecorder()
+	rq := httptest.NewRequest("POST", "/person/12?page=3&tag=a,b&tag=c", strings.NewReader(`{"name": "bob"}`))
+	rq.Header.Set("X-Trace", "abc")
+	rt.ServeHTTP(w, rq)
+
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"name":"bob"`) {
+		t.Fatal("unexpected response", w.Code, w.Body.String())
+	}
+
+	if got.ID != 12 || got.Page != 3 || strings.Join(got.Tags, ",") != "a,b,c" || got.TraceID != "abc" {
+		t.Fatal("unexpected input", got)
+	}
+
+	w = httptest.NewRecorder()
+	rt.ServeHTTP(w, httptest.NewRequest("POST", "/person/12?page=x", strings.NewReader(`{}`)))
+
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"field":"page"`) {
+		t.Fatal("expected a binding error", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	rt.ServeHTTP(w, httptest.NewRequest("POST", "/person/12", strings.NewReader(`{"name": "fail"}`)))
+
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "name can't be fail") {
+		t.Fatal("expected the client-safe error", w.Code, w.Body.String())
+	}
+}
@@ -1,6 +1,7 @@
 package res
 
 import (
+	"context"
 	"net/http"
 	"time"
 )
@@ -37,9 +38,32 @@ func NoCacheFunc(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+type noCacheHeadersKeyType string
+
+const noCacheHeadersKey noCacheHeadersKeyType = "res-no-cache-headers"
+
+// NoCache sets the NoCacheFunc headers for every response.
+//
+// The conditional request headers it strips are still seen by Conditional (r.ETag()) and CheckIfMatch, so
+// ETag routes keep returning 304 and 412. Conditional replaces the no-store Cache-Control with no-cache,
+// so browsers keep the response and revalidate it instead.
 func NoCache(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(context.WithValue(r.Context(), noCacheHeadersKey, r.Header.Clone()))
 		NoCacheFunc(w, r)
 		h.ServeHTTP(w, r)
 	})
 }
+
+// conditionalHeader gets a request header, including the ones NoCache stripped
+func conditionalHeader(r *http.Request, name string) string {
+	if value := r.Header.Get(name); value != "" {
+		return value
+	}
+
+	if header, ok := r.Context().Value(noCacheHeadersKey).(http.Header); ok {
+		return header.Get(name)
+	}
+
+	return ""
+}
@@ -0,0 +1,140 @@
+package r
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/ntbosscher/gobase/auth"
+	"github.com/ntbosscher/gobase/er"
+	errors2 "github.com/pkg/errors"
+)
+
+// Group adds routes that share a path prefix and route config
+type Group struct {
+	router *Router
+	prefix string
+
+	// the group's middleware followed by its parents', so outer groups wrap inner ones
+	middleware     []RouteConfig
+	defaultVersion *VersionedHandler
+}
+
+// Group creates a route group. Config can contain:
+//   - Middleware (e.g. RequireRole, RateLimit): applied to every route in the group, in the same order as Add
+//   - auth.TRole: the role required for every route in the group, on top of the route's own role.
+//     auth.Public skips authentication for the whole prefix.
+//   - DefaultVersion(handler): used by the group's Versioned routes that don't have their own DefaultVersion
+//   - func(g *Group): called with the group to add its routes
+//
+// Groups can be nested, the inner group's path and config are added to the outer group's.
+//
+//	rt.Group("/api/admin", r.RequireRole(RoleAdmin), r.RateLimit(100, time.Minute), func(g *r.Group) {
+//		g.Add("GET", "/users", listUsers)
+//		g.Group("/reports", r.DefaultVersion(reportsGone), func(g *r.Group) {
+//			g.Add("GET", "/sales", r.Version("2", salesReport))
+//		})
+//	})
+func (r *Router) Group(prefix string, config ...RouteConfig) *Group {
+	return newGroup(r, "", nil, nil, prefix, config)
+}
+
+// Group creates a nested group, see Router.Group
+func (g *Group) Group(prefix string, config ...RouteConfig) *Group {
+	return newGroup(g.router, g.prefix, g.middleware, g.defaultVersion, prefix, config)
+}
+
+func newGroup(router *Router, parentPrefix string, parentMiddleware []RouteConfig, defaultVersion *VersionedHandler, prefix string, config []RouteConfig) *Group {
+	if !strings.HasPrefix(prefix, "/") {
+		panic("group prefix must start with /")
+	}
+
+	g := &Group{
+		router:         router,
+		prefix:         parentPrefix + strings.TrimSuffix(prefix, "/"),
+		defaultVersion: defaultVersion,
+	}
+
+	var setup []func(g *Group)
+
+	for _, item := range flattenConfig(config) {
+		switch v := item.(type) {
+		case func(g *Group):
+			setup = append(setup, v)
+		case auth.TRole:
+			if v == auth.Public {
+				router.ignoreAuthForPrefix(g.prefix)
+				continue
+			}
+
+			g.middleware = append(g.middleware, RequireRole(v))
+		case Middleware:
+			g.middleware = append(g.middleware, v)
+		case VersionedHandler:
+			if !v.isDefault {
+				er.Throw("group config only accepts DefaultVersion(...), add Version(...) to the group's routes")
+			}
+
+			value := v
+			g.defaultVersion = &value
+		default:
+			err := errors.New(strings.Join([]string{"warning: group", g.prefix, "unrecognized group option type", reflect.TypeOf(item).String()}, " "))
+			err = errors2.WithStack(err)
+			logger.Printf("%+v", err)
+			logger.Println()
+		}
+	}
+
+	g.middleware = append(g.middleware, parentMiddleware...)
+
+	for _, fx := range setup {
+		fx(g)
+	}
+
+	return g
+}
+
+// Add adds a route to the group, path is relative to the group's prefix. See Router.Add
+func (g *Group) Add(method string, path string, config ...RouteConfig) {
+	items := flattenConfig(config)
+
+	if g.defaultVersion != nil {
+		hasVersions := false
+		hasDefault := false
+
+		for _, item := range items {
+			if v, ok := item.(VersionedHandler); ok {
+				hasVersions = true
+				hasDefault = hasDefault || v.isDefault
+			}
+		}
+
+		if hasVersions && !hasDefault {
+			items = append(items, *g.defaultVersion)
+		}
+	}
+
+	items = append(items, g.middleware...)
+	g.router.Add(method, g.prefix+path, items...)
+}
+
+// Prefix is the full path prefix of the group
+func (g *Group) Prefix() string {
+	return g.prefix
+}
+
+// flattenConfig expands nested []RouteConfig items in place so the order of middleware is kept
+func flattenConfig(config []RouteConfig) []RouteConfig {
+	var list []RouteConfig
+
+	for _, item := range config {
+		if nested, ok := item.([]RouteConfig); ok {
+			list = append(list, flattenConfig(nested)...)
+			continue
+		}
+
+		list = append(list, item)
+	}
+
+	return list
+}
@@ -0,0 +1,92 @@
+package r
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ntbosscher/gobase/apiversion"
+	"github.com/ntbosscher/gobase/auth"
+	"github.com/ntbosscher/gobase/auth/httpauth"
+	"github.com/ntbosscher/gobase/res"
+)
+
+func newTestAuthRouter(t *testing.T) *Router {
+	httpauth.SetJWTKey([]byte(strings.Repeat("k", 64)))
+	t.Cleanup(func() {
+		httpauth.SetJWTKey(nil)
+	})
+
+	rt := NewRouter()
+	rt.Use(apiversion.Middleware())
+	rt.WithAuth(httpauth.Config{
+		CredentialChecker: func(ctx context.Context, credential *httpauth.Credential) (*auth.UserInfo, error) {
+			return nil, nil
+		},
+	})
+
+	return rt
+}
+
+func TestGroup(t *testing.T) {
+	rt := newTestAuthRouter(t)
+
+	var calls []string
+	record := func(name string) Middleware {
+		return func(router *Router, method string, path string, next res.HandlerFunc2) res.HandlerFunc2 {
+			return func(rq *res.Request) res.Responder {
+				calls = append(calls, name)
+				return next(rq)
+			}
+		}
+	}
+
+	ok := func(rq *res.Request) res.Responder {
+		return res.Ok()
+	}
+
+	gone := func(rq *res.Request) res.Responder {
+		return res.WithCode(http.StatusGone)
+	}
+
+	rt.Group("/api/public", auth.Public, record("outer"), func(g *Group) {
+		g.Add("GET", "/ping", ok, record("route"))
+
+		g.Group("/v", DefaultVersion(gone), record("inner"), func(g *Group) {
+			g.Add("GET", "/thing", Version("2", ok))
+		})
+	})
+
+	rt.Group("/api/admin", RequireRole(auth.RoleAny), func(g *Group) {
+		g.Add("GET", "/users", ok)
+	})
+
+	get := func(path string) int {
+		w := httptest.NewRecorder()
+		rt.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Code
+	}
+
+	if code := get("/api/public/ping"); code != http.StatusOK {
+		t.Fatal("expected the public group to skip auth, got", code)
+	}
+
+	if strings.Join(calls, ",") != "outer,route" {
+		t.Fatal("unexpected middleware order", calls)
+	}
+
+	calls = nil
+	if code := get("/api/public/v/thing"); code != http.StatusGone {
+		t.Fatal("expected the group's default version, got", code)
+	}
+
+	if strings.Join(calls, ",") != "outer,inner" {
+		t.Fatal("unexpected middleware order", calls)
+	}
+
+	if code := get("/api/admin/users"); code != http.StatusUnauthorized {
+		t.Fatal("expected the admin group to require auth, got", code)
+	}
+}
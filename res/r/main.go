@@ -29,6 +29,7 @@ type Router struct {
 	*res.Router
 	auth     *httpauth.AuthRouter
 	hasRoute bool
+	docs     routeDocs
 }
 
 func NewRouter() *Router {
@@ -63,6 +64,9 @@ func (r *Router) Add(method string, path string, config ...RouteConfig) {
 
 	requiredRole := auth.Public
 	var handler res.HandlerFunc2
+	var versioned []VersionedHandler
+	var typed *typedRoute
+	var doc *Doc
 
 	configs := [][]RouteConfig{config}
 
@@ -78,6 +82,12 @@ func (r *Router) Add(method string, path string, config ...RouteConfig) {
 				// add at the end so we can OR all the roles that come along
 			case Middleware:
 				cfg.next = append(cfg.next, v)
+			case VersionedHandler:
+				versioned = append(versioned, v)
+			case typedRoute:
+				typed = &v
+			case Doc:
+				doc = &v
 			default:
 				err := errors.New(strings.Join([]string{"warning: route", method, path, "unrecognized route option type", reflect.TypeOf(item).String()}, " "))
 				err = errors2.WithStack(err)
@@ -87,10 +97,29 @@ func (r *Router) Add(method string, path string, config ...RouteConfig) {
 		}
 	}
 
+	if len(versioned) > 0 {
+		if handler != nil {
+			er.Throw("route config can't have both a res.HandlerFunc2 and VersionedHandlers")
+		}
+
+		handler = Versioned(versioned...)
+	}
+
 	if handler == nil {
 		er.Throw("missing type(res.HandlerFunc2) parameter for route config")
 	}
 
+	r.routeDoc(method, path, func(d *routeDoc) {
+		d.typed = typed
+		if doc != nil {
+			d.doc = *doc
+		}
+
+		for _, v := range versioned {
+			d.versions = append(d.versions, v.version)
+		}
+	})
+
 	cfg.Add(RequireRole(requiredRole))
 	cfg.Handler(handler)
 }
@@ -99,6 +128,11 @@ func (r *Router) ignoreAuthForRoute(path string) {
 	r.auth.AddIgnoreRoute(path)
 }
 
+func (r *Router) ignoreAuthForPrefix(prefix string) {
+	r.auth.AddIgnoreRoute(prefix)
+	r.auth.AddIgnoreRoutePrefix(prefix + "/")
+}
+
 func (r *Router) GithubContinuousDeployment(input res.GithubCDInput) {
 	input.Path = strs.Coalesce(input.Path, res.DefaultGithubCdPath)
 	r.ignoreAuthForRoute(input.Path)
@@ -116,6 +150,7 @@ func (r *Router) Route(method string, path string) *Configure {
 
 	return &Configure{
 		callback: func(c *Configure, handler res.HandlerFunc2) {
+			r.routeDoc(method, path, func(doc *routeDoc) {})
 
 			for _, cfg := range c.next {
 				handler = cfg(r, method, path, handler)
@@ -142,6 +177,21 @@ func (c *Configure) Handler(handler res.HandlerFunc2) {
 
 func RequireRole(role auth.TRole) Middleware {
 	return func(router *Router, method, path string, next res.HandlerFunc2) res.HandlerFunc2 {
+		guarded := false
+
+		router.routeDoc(method, path, func(doc *routeDoc) {
+			guarded = len(doc.roles) > 0
+			if role != auth.Public {
+				doc.roles = append(doc.roles, role)
+			}
+		})
+
+		if role == auth.Public && guarded {
+			// a RequireRole earlier in the chain (e.g. from a Group) already guards this
+			// route, so auth can't be skipped for it
+			return next
+		}
+
 		return router.auth.RequireRole(path, role, next)
 	}
 }
@@ -196,6 +246,7 @@ func Versioned(versionedHandlers ...VersionedHandler) res.HandlerFunc2 {
 func RateLimit(n int, window time.Duration) Middleware {
 	return func(r *Router, method, path string, next res.HandlerFunc2) res.HandlerFunc2 {
 		limiter := ratelimit.NewKeyed(n, window)
+		r.documentRateLimit(method, path, n, window, false)
 
 		return func(rq *res.Request) res.Responder {
 
@@ -213,6 +264,7 @@ func RateLimit(n int, window time.Duration) Middleware {
 func RateLimitErr(n int, window time.Duration) Middleware {
 	return func(r *Router, method, path string, next res.HandlerFunc2) res.HandlerFunc2 {
 		limiter := ratelimit.NewKeyed(n, window)
+		r.documentRateLimit(method, path, n, window, true)
 
 		return func(rq *res.Request) res.Responder {
 
@@ -234,6 +286,26 @@ func RateLimitErr(n int, window time.Duration) Middleware {
 	}
 }
 
+// ETag enables conditional requests for a route (see res.Conditional): Ok/List responses
+// get an ETag, and GET requests with a matching If-None-Match or If-Modified-Since
+// (see res.WithLastModified) get a 304. PUT handlers can use res.CheckIfMatch to reject
+// stale edits with a 412.
+//
+//	rt.Add("GET", "/api/person/{id:[0-9]+}", r.ETag(), getPerson)
+func ETag() Middleware {
+	return func(r *Router, method, path string, next res.HandlerFunc2) res.HandlerFunc2 {
+		return func(rq *res.Request) res.Responder {
+			return res.Conditional(next(rq))
+		}
+	}
+}
+
+func (r *Router) documentRateLimit(method string, path string, n int, window time.Duration, errorsOnly bool) {
+	r.routeDoc(method, path, func(doc *routeDoc) {
+		doc.rateLimits = append(doc.rateLimits, &rateLimitDoc{Limit: n, Window: window.String(), ErrorsOnly: errorsOnly})
+	})
+}
+
 // clientKey identifies the caller so the rate limiters bucket per-client instead
 // of sharing one global bucket across everyone. It uses the trusted-proxy-aware
 // client IP from requestip (falling back to the raw peer address). Without the
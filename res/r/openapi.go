@@ -0,0 +1,494 @@
+package r
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ntbosscher/gobase/apiversion"
+	"github.com/ntbosscher/gobase/auth"
+	"github.com/ntbosscher/gobase/res"
+)
+
+type typedRoute struct {
+	in  reflect.Type
+	out reflect.Type
+}
+
+// Typed declares the request and response types of a route for the OpenAPI document (see Router.OpenAPI)
+//
+//	rt.Add("POST", "/api/person", RoleUser, r.Typed[CreatePersonInput, *Person](), createPerson)
+//
+// Use struct{} for In when the route has no input.
+func Typed[In any, Out any]() RouteConfig {
+	return typedRoute{
+		in:  reflect.TypeOf((*In)(nil)).Elem(),
+		out: reflect.TypeOf((*Out)(nil)).Elem(),
+	}
+}
+
+// Handle is res.Handle that also declares the route's types for the OpenAPI document (see Typed)
+//
+//	rt.Add("GET", "/api/person/{id:[0-9]+}", RoleUser, r.Handle(getPerson))
+func Handle[In any, Out any](handler func(ctx context.Context, input In) (Out, error)) []RouteConfig {
+	return []RouteConfig{
+		Typed[In, Out](),
+		res.Handle(handler),
+	}
+}
+
+// Doc adds a description to a route's OpenAPI operation
+type Doc struct {
+	Summary     string
+	Description string
+	Tags        []string
+	Deprecated  bool
+}
+
+type routeDoc struct {
+	method     string
+	path       string
+	roles      []auth.TRole // one per RequireRole, the caller must pass all of them
+	typed      *typedRoute
+	versions   []string
+	rateLimits []*rateLimitDoc
+	doc        Doc
+	hidden     bool
+}
+
+type rateLimitDoc struct {
+	Limit      int    `json:"limit"`
+	Window     string `json:"window"`
+	ErrorsOnly bool   `json:"errorsOnly,omitempty"`
+}
+
+type routeDocs struct {
+	mu     sync.Mutex
+	list   []*routeDoc
+	byPath map[string]*routeDoc
+}
+
+// routeDoc returns the documentation entry for the route, creating it if needed
+func (r *Router) routeDoc(method string, path string, update func(doc *routeDoc)) {
+	r.docs.mu.Lock()
+	defer r.docs.mu.Unlock()
+
+	if r.docs.byPath == nil {
+		r.docs.byPath = map[string]*routeDoc{}
+	}
+
+	key := method + " " + path
+	doc := r.docs.byPath[key]
+
+	if doc == nil {
+		doc = &routeDoc{method: method, path: path}
+		r.docs.byPath[key] = doc
+		r.docs.list = append(r.docs.list, doc)
+	}
+
+	update(doc)
+}
+
+type OpenAPIOptions struct {
+	Title       string
+	Version     string
+	Description string
+
+	// Servers are the base urls of the api (e.g. https://api.example.com)
+	Servers []string
+
+	// RoleNames names the roles used with RequireRole/Add. Unnamed roles show up as role:<bit value>.
+	RoleNames map[auth.TRole]string
+
+	// Role restricts who can see the document
+	// default: auth.Public
+	Role auth.TRole
+
+	// ViewerAssetsURL is where the swagger-ui-dist files are loaded from
+	// default: https://unpkg.com/swagger-ui-dist@5
+	ViewerAssetsURL string
+}
+
+// OpenAPI serves an OpenAPI 3.1 document of the routes added with Add at path+".json" and
+// a viewer for it at path. The document is built on request, so routes added after OpenAPI are included.
+//
+//	rt.OpenAPI("/api/docs", &r.OpenAPIOptions{Title: "Example", RoleNames: map[auth.TRole]string{RoleUser: "user"}})
+func (r *Router) OpenAPI(path string, opts *OpenAPIOptions) {
+	if opts == nil {
+		opts = &OpenAPIOptions{}
+	}
+
+	jsonPath := path + ".json"
+
+	r.addDocsRoute(jsonPath, opts.Role, func(rq *res.Request) res.Responder {
+		return res.Ok(r.OpenAPIDocument(opts))
+	})
+
+	assets := strings.TrimSuffix(opts.ViewerAssetsURL, "/")
+	if assets == "" {
+		assets = "https://unpkg.com/swagger-ui-dist@5"
+	}
+
+	page := &strings.Builder{}
+	err := viewerTemplate.Execute(page, map[string]string{
+		"Title":   opts.Title,
+		"Assets":  assets,
+		"SpecURL": jsonPath,
+	})
+
+	if err != nil {
+		panic(err)
+	}
+
+	r.addDocsRoute(path, opts.Role, func(rq *res.Request) res.Responder {
+		return res.Html(page.String())
+	})
+}
+
+func (r *Router) addDocsRoute(path string, role auth.TRole, handler res.HandlerFunc2) {
+	if r.auth == nil {
+		r.Router.Route("GET", path, handler)
+	} else {
+		r.Add("GET", path, role, handler)
+	}
+
+	r.routeDoc("GET", path, func(doc *routeDoc) {
+		doc.hidden = true
+	})
+}
+
+var viewerTemplate = template.Must(template.New("openapi").Parse(`<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="utf-8">
+	<title>{{.Title}}</title>
+	<link rel="stylesheet" href="{{.Assets}}/swagger-ui.css">
+</head>
+<body>
+	<div id="swagger-ui"></div>
+	<script src="{{.Assets}}/swagger-ui-bundle.js"></script>
+	<script>
+		SwaggerUIBundle({url: {{.SpecURL}}, dom_id: "#swagger-ui"});
+	</script>
+</body>
+</html>
+`))
+
+type OpenAPIDocument struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       OpenAPIInfo                      `json:"info"`
+	Servers    []OpenAPIServer                  `json:"servers,omitempty"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components OpenAPIComponents                `json:"components"`
+}
+
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type OpenAPIServer struct {
+	URL string `json:"url"`
+}
+
+type OpenAPIComponents struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+}
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+
+	// Roles lists the role checks of the route (e.g. the group's and the route's own), the caller must
+	// pass every check by having any of its roles
+	Roles      [][]string      `json:"x-roles,omitempty"`
+	RateLimits []*rateLimitDoc `json:"x-rate-limit,omitempty"`
+	Versions   []string        `json:"x-api-versions,omitempty"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// OpenAPIDocument builds the OpenAPI 3.1 document for the routes added so far
+func (r *Router) OpenAPIDocument(opts *OpenAPIOptions) *OpenAPIDocument {
+	if opts == nil {
+		opts = &OpenAPIOptions{}
+	}
+
+	doc := &OpenAPIDocument{
+		OpenAPI: "3.1.0",
+		Info: OpenAPIInfo{
+			Title:       opts.Title,
+			Version:     opts.Version,
+			Description: opts.Description,
+		},
+		Paths: map[string]map[string]*Operation{},
+	}
+
+	if doc.Info.Title == "" {
+		doc.Info.Title = "API"
+	}
+
+	if doc.Info.Version == "" {
+		doc.Info.Version = "1.0.0"
+	}
+
+	for _, server := range opts.Servers {
+		doc.Servers = append(doc.Servers, OpenAPIServer{URL: server})
+	}
+
+	schemas := newSchemaBuilder()
+
+	r.docs.mu.Lock()
+	routes := append([]*routeDoc{}, r.docs.list...)
+	r.docs.mu.Unlock()
+
+	for _, route := range routes {
+		if route.hidden {
+			continue
+		}
+
+		path, params := openAPIPath(route.path)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]*Operation{}
+		}
+
+		doc.Paths[path][strings.ToLower(route.method)] = route.operation(opts, schemas, path, params)
+	}
+
+	schemas.components["Error"] = schemas.structSchema(reflect.TypeOf(errorResponse{}))
+
+	doc.Components = OpenAPIComponents{
+		Schemas: schemas.components,
+		SecuritySchemes: map[string]SecurityScheme{
+			"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+		},
+	}
+
+	return doc
+}
+
+// errorResponse is the body of res error responses
+type errorResponse struct {
+	Error      string      `json:"error"`
+	Message    string      `json:"message"`
+	StackTrace string      `json:"stackTrace"`
+	Details    interface{} `json:"details"`
+}
+
+var nonIdentifierChars = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+func (route *routeDoc) operation(opts *OpenAPIOptions, schemas *schemaBuilder, path string, params []*Parameter) *Operation {
+	op := &Operation{
+		OperationID: strings.Trim(nonIdentifierChars.ReplaceAllString(strings.ToLower(route.method)+"_"+path, "_"), "_"),
+		Summary:     route.doc.Summary,
+		Description: route.doc.Description,
+		Tags:        route.doc.Tags,
+		Deprecated:  route.doc.Deprecated,
+		Parameters:  params,
+		RateLimits:  route.rateLimits,
+		Versions:    route.versions,
+		Responses: map[string]*Response{
+			"default": {
+				Description: "Error",
+				Content:     jsonContent(&Schema{Ref: "#/components/schemas/Error"}),
+			},
+		},
+	}
+
+	if len(route.roles) > 0 {
+		for _, role := range route.roles {
+			op.Roles = append(op.Roles, roleNames(role, opts.RoleNames))
+		}
+
+		// scopes would mean "all of", but a role check only needs one of its roles, so they're in x-roles instead
+		op.Security = []map[string][]string{{"bearerAuth": {}}}
+		op.Responses["401"] = &Response{Description: "Not authorized"}
+	}
+
+	if len(route.rateLimits) > 0 {
+		op.Responses["429"] = &Response{Description: "Too many requests"}
+	}
+
+	if len(route.versions) > 0 {
+		versions := []interface{}{}
+		for _, v := range route.versions {
+			if v != "" {
+				versions = append(versions, v)
+			}
+		}
+
+		op.Parameters = append(op.Parameters, &Parameter{
+			Name:   apiversion.VersionHeaderName,
+			In:     "header",
+			Schema: &Schema{Type: "string", Enum: versions},
+		})
+	}
+
+	op.Responses["200"] = &Response{Description: "OK"}
+
+	if route.typed == nil {
+		return op
+	}
+
+	if route.typed.out.Kind() != reflect.Interface {
+		op.Responses["200"].Content = jsonContent(schemas.schema(route.typed.out))
+	}
+
+	in := route.typed.in
+	if in.Kind() == reflect.Ptr {
+		in = in.Elem()
+	}
+
+	if in.Kind() == reflect.Struct && in.NumField() == 0 {
+		return op
+	}
+
+	switch route.method {
+	case http.MethodGet, http.MethodDelete, http.MethodHead:
+		op.Parameters = append(op.Parameters, queryParameters(schemas, in, params)...)
+	default:
+		op.RequestBody = &RequestBody{Required: true, Content: jsonContent(schemas.schema(route.typed.in))}
+	}
+
+	return op
+}
+
+func jsonContent(schema *Schema) map[string]*MediaType {
+	return map[string]*MediaType{
+		"application/json": {Schema: schema},
+	}
+}
+
+// queryParameters describes the fields of a GET route's input as query parameters. Fields bound
+// by res.Handle from the path or headers are left out, `query:"name"` tags rename the parameter.
+func queryParameters(schemas *schemaBuilder, in reflect.Type, pathParams []*Parameter) []*Parameter {
+	if in.Kind() != reflect.Struct {
+		return nil
+	}
+
+	inPath := map[string]bool{}
+	for _, p := range pathParams {
+		inPath[p.Name] = true
+	}
+
+	var list []*Parameter
+
+	for i := 0; i < in.NumField(); i++ {
+		f := in.Field(i)
+
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			list = append(list, queryParameters(schemas, f.Type, pathParams)...)
+			continue
+		}
+
+		if !f.IsExported() || f.Tag.Get("path") != "" || f.Tag.Get("header") != "" || f.Tag.Get("json") == "-" {
+			continue
+		}
+
+		name := f.Tag.Get("query")
+		if name == "" {
+			name, _, _ = strings.Cut(f.Tag.Get("json"), ",")
+		}
+
+		if name == "" {
+			name = res.JsonRenameKeysToCamelCase(f.Name)
+		}
+
+		if inPath[name] {
+			continue
+		}
+
+		list = append(list, &Parameter{Name: name, In: "query", Required: isRequired(f), Schema: schemas.schema(f.Type)})
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+
+	return list
+}
+
+var gorillaPathVar = regexp.MustCompile(`\{([^{}:]+)(:[^{}]*)?\}`)
+var numericPattern = regexp.MustCompile(`^:\[0-9\][+*]$|^:\\d[+*]$`)
+
+// openAPIPath converts a gorilla path (/api/person/{id:[0-9]+}) to an OpenAPI path (/api/person/{id})
+func openAPIPath(path string) (string, []*Parameter) {
+	var params []*Parameter
+
+	converted := gorillaPathVar.ReplaceAllStringFunc(path, func(match string) string {
+		parts := gorillaPathVar.FindStringSubmatch(match)
+
+		schema := &Schema{Type: "string"}
+		if numericPattern.MatchString(parts[2]) {
+			schema = &Schema{Type: "integer"}
+		}
+
+		params = append(params, &Parameter{Name: parts[1], In: "path", Required: true, Schema: schema})
+		return "{" + parts[1] + "}"
+	})
+
+	return converted, params
+}
+
+func roleNames(role auth.TRole, names map[auth.TRole]string) []string {
+	if role == auth.RoleAny {
+		return []string{"any"}
+	}
+
+	if name, ok := names[role]; ok {
+		return []string{name}
+	}
+
+	list := []string{}
+	for _, single := range auth.SplitRole(role) {
+		if name, ok := names[single]; ok {
+			list = append(list, name)
+		} else {
+			list = append(list, fmt.Sprintf("role:%d", single))
+		}
+	}
+
+	return list
+}
@@ -0,0 +1,111 @@
+package r
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/ntbosscher/gobase/auth"
+	"github.com/ntbosscher/gobase/res"
+)
+
+type openAPIAddress struct {
+	Street string
+}
+
+type openAPIPerson struct {
+	ID        int
+	FirstName string
+	Email     string `json:"emailAddress"`
+	Secret    string `json:"-"`
+	Phone     nulls.String
+	Manager   *openAPIPerson
+	Addresses []openAPIAddress
+	CreatedAt time.Time
+}
+
+func TestOpenAPIPath(t *testing.T) {
+	path, params := openAPIPath("/api/company/{company}/person/{id:[0-9]+}")
+	if path != "/api/company/{company}/person/{id}" {
+		t.Fatal("unexpected path", path)
+	}
+
+	if len(params) != 2 || params[0].Name != "company" || params[1].Schema.Type != "integer" {
+		t.Fatal("unexpected params", params)
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	const roleUser auth.TRole = 1 << 1
+	const roleManager auth.TRole = 1 << 2
+	const roleAdmin auth.TRole = 1 << 3
+
+	ok := func(rq *res.Request) res.Responder {
+		return res.Ok()
+	}
+
+	rt := newTestAuthRouter(t)
+	rt.Add("POST", "/api/person", roleUser, RateLimit(10, time.Minute), Typed[openAPIPerson, *openAPIPerson](), ok)
+	rt.Add("GET", "/api/person/{id:[0-9]+}", Typed[struct{ ID int }, openAPIPerson](), DefaultVersion(ok), Version("2", ok))
+
+	rt.Group("/api/admin", roleAdmin, func(g *Group) {
+		g.Add("DELETE", "/person/{id:[0-9]+}", roleUser|roleManager, ok)
+	})
+
+	doc := rt.OpenAPIDocument(&OpenAPIOptions{RoleNames: map[auth.TRole]string{roleUser: "user", roleManager: "manager", roleAdmin: "admin"}})
+
+	post := doc.Paths["/api/person"]["post"]
+	if post == nil || len(post.Security) != 1 || post.RateLimits[0].Limit != 10 || post.RateLimits[0].Window != "1m0s" {
+		js, _ := json.Marshal(post)
+		t.Fatal("unexpected post operation", string(js))
+	}
+
+	if js, _ := json.Marshal(post.Roles); string(js) != `[["user"]]` {
+		t.Fatal("unexpected post roles", string(js))
+	}
+
+	if post.RequestBody.Content["application/json"].Schema.Ref != "#/components/schemas/openAPIPerson" {
+		t.Fatal("unexpected request body", post.RequestBody)
+	}
+
+	get := doc.Paths["/api/person/{id}"]["get"]
+	if get == nil || get.Security != nil || get.Roles != nil || len(get.Parameters) != 2 || get.Parameters[1].In != "header" {
+		js, _ := json.Marshal(get)
+		t.Fatal("unexpected get operation", string(js))
+	}
+
+	if js, _ := json.Marshal(get.Versions); string(js) != `["","2"]` {
+		t.Fatal("unexpected get versions", string(js))
+	}
+
+	// the group's role check and the route's are both required
+	del := doc.Paths["/api/admin/person/{id}"]["delete"]
+	if del == nil || len(del.Security) != 1 {
+		js, _ := json.Marshal(del)
+		t.Fatal("unexpected delete operation", string(js))
+	}
+
+	if js, _ := json.Marshal(del.Roles); string(js) != `[["admin"],["user","manager"]]` {
+		t.Fatal("unexpected delete roles", string(js))
+	}
+
+	person := doc.Components.Schemas["openAPIPerson"]
+	for _, name := range []string{"id", "firstName", "emailAddress", "phone", "manager", "addresses", "createdAt"} {
+		if person.Properties[name] == nil {
+			t.Fatal("missing property", name)
+		}
+	}
+
+	if person.Properties["secret"] != nil {
+		t.Fatal("json:\"-\" fields should be skipped")
+	}
+
+	if js, _ := json.Marshal(person.Properties["phone"].Type); string(js) != `["string","null"]` {
+		t.Fatal("expected nullable string, got", string(js))
+	}
+
+	if person.Properties["addresses"].Items.Ref != "#/components/schemas/openAPIAddress" {
+		t.Fatal("unexpected addresses schema", person.Properties["addresses"])
+	}
+}
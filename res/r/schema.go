@@ -0,0 +1,301 @@
+package r
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ntbosscher/gobase/res"
+)
+
+// Schema is an OpenAPI 3.1 (json schema) object
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 interface{}        `json:"type,omitempty"` // string, or []string for nullable types
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+}
+
+var muSchemaOverrides sync.RWMutex
+var schemaOverrides = map[reflect.Type]*Schema{}
+
+// RegisterSchema sets the OpenAPI schema used for the type of example. Use it for types
+// with custom json marshalling that aren't detected correctly.
+//
+//	r.RegisterSchema(currency.Cents(0), &r.Schema{Type: "integer", Description: "cents"})
+func RegisterSchema(example interface{}, schema *Schema) {
+	muSchemaOverrides.Lock()
+	defer muSchemaOverrides.Unlock()
+
+	schemaOverrides[reflect.TypeOf(example)] = schema
+}
+
+func schemaOverride(t reflect.Type) *Schema {
+	muSchemaOverrides.RLock()
+	defer muSchemaOverrides.RUnlock()
+
+	return schemaOverrides[t]
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// schemaBuilder reflects go types into schemas. Named structs are added to components
+// and referenced with $ref.
+type schemaBuilder struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{
+		components: map[string]*Schema{},
+		names:      map[reflect.Type]string{},
+	}
+}
+
+func (b *schemaBuilder) schema(t reflect.Type) *Schema {
+	if s := schemaOverride(t); s != nil {
+		return s
+	}
+
+	if t.Kind() == reflect.Ptr {
+		return nullable(b.schema(t.Elem()))
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType:
+		return &Schema{}
+	case implements(t, jsonMarshalerType):
+		return b.marshalerSchema(t)
+	case implements(t, textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+
+		return &Schema{Type: "array", Items: b.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schema(t.Elem())}
+	case reflect.Struct:
+		return b.structRef(t)
+	default:
+		// interface{}, funcs, channels: anything goes
+		return &Schema{}
+	}
+}
+
+// marshalerSchema guesses the schema for types with custom json encoding. The nulls.* and
+// model.Null* types (a value + Valid bool) are treated as a nullable value.
+func (b *schemaBuilder) marshalerSchema(t reflect.Type) *Schema {
+	if t.ConvertibleTo(timeType) {
+		if strings.Contains(t.Name(), "Date") {
+			return &Schema{Type: "string", Format: "date"}
+		}
+
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	if t.Kind() != reflect.Struct {
+		// e.g. type Cents int with a MarshalJSON
+		return b.schema(basicType(t.Kind()))
+	}
+
+	if t.NumField() == 2 {
+		var value *reflect.StructField
+
+		for i := 0; i < 2; i++ {
+			f := t.Field(i)
+			if f.Name == "Valid" && f.Type.Kind() == reflect.Bool {
+				continue
+			}
+
+			value = &f
+		}
+
+		if value != nil && value.Name != "Valid" {
+			return nullable(b.schema(value.Type))
+		}
+	}
+
+	return &Schema{}
+}
+
+func basicType(kind reflect.Kind) reflect.Type {
+	switch kind {
+	case reflect.Bool:
+		return reflect.TypeOf(false)
+	case reflect.String:
+		return reflect.TypeOf("")
+	case reflect.Float32, reflect.Float64:
+		return reflect.TypeOf(float64(0))
+	case reflect.Slice:
+		return reflect.TypeOf([]interface{}{})
+	case reflect.Map:
+		return reflect.TypeOf(map[string]interface{}{})
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return reflect.TypeOf(int64(0))
+	default:
+		return reflect.TypeOf((*interface{})(nil)).Elem()
+	}
+}
+
+func implements(t reflect.Type, iface reflect.Type) bool {
+	return t.Implements(iface) || reflect.PointerTo(t).Implements(iface)
+}
+
+func nullable(s *Schema) *Schema {
+	if s.Ref != "" || s.Type == nil {
+		return s
+	}
+
+	if typ, ok := s.Type.(string); ok {
+		clone := *s
+		clone.Type = []string{typ, "null"}
+		return &clone
+	}
+
+	return s
+}
+
+var componentNameCleaner = regexp.MustCompile(`[^A-Za-z0-9_.\-]+`)
+
+func (b *schemaBuilder) structRef(t reflect.Type) *Schema {
+	if t.Name() == "" {
+		return b.structSchema(t)
+	}
+
+	name, ok := b.names[t]
+	if !ok {
+		name = b.componentName(t)
+		b.names[t] = name
+
+		// reserve the name before recursing so self-referencing types terminate
+		b.components[name] = &Schema{}
+		*b.components[name] = *b.structSchema(t)
+	}
+
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+func (b *schemaBuilder) componentName(t reflect.Type) string {
+	name := componentNameCleaner.ReplaceAllString(t.Name(), "_")
+
+	if _, taken := b.components[name]; !taken {
+		return name
+	}
+
+	pkg := t.PkgPath()
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+
+	name = componentNameCleaner.ReplaceAllString(pkg+"."+t.Name(), "_")
+	if _, taken := b.components[name]; !taken {
+		return name
+	}
+
+	for i := 2; ; i++ {
+		candidate := name + strconv.Itoa(i)
+		if _, taken := b.components[candidate]; !taken {
+			return candidate
+		}
+	}
+}
+
+func (b *schemaBuilder) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	b.addFields(s, t)
+	return s
+}
+
+// addFields follows the json encoding res uses (jsoniter with JsonRenameKeysToCamelCase)
+func (b *schemaBuilder) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				b.addFields(s, ft)
+				continue
+			}
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = res.JsonRenameKeysToCamelCase(f.Name)
+		}
+
+		if strings.Contains(","+opts+",", ",string,") {
+			s.Properties[name] = &Schema{Type: "string"}
+			continue
+		}
+
+		s.Properties[name] = b.schema(f.Type)
+
+		if isRequired(f) {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// isRequired reads the res.Validate "required" rule
+func isRequired(f reflect.StructField) bool {
+	for _, rule := range strings.Split(f.Tag.Get(res.ValidationTag), ",") {
+		if rule == "required" {
+			return true
+		}
+
+		if rule == "dive" || strings.HasPrefix(rule, "regexp=") {
+			return false
+		}
+	}
+
+	return false
+}
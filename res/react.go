@@ -43,7 +43,7 @@ func ReactSmoothTransitionBuildFolder(value string) ReactConfig {
 func ReactApp(dir string, testNodeServerAddr string, cfg ...ReactConfig) http.Handler {
 
 	rr := &reactRouter{
-		fileServer:         http.FileServer(http.Dir(dir)),
+		fileServer:         newPrecompressedFileServer(dir),
 		staticDir:          dir,
 		testNodeServerAddr: testNodeServerAddr,
 		indexFile: func(r *http.Request) string {
@@ -57,7 +57,7 @@ func ReactApp(dir string, testNodeServerAddr string, cfg ...ReactConfig) http.Ha
 			rr.indexFile = value
 		case reactSmoothTransitionBuildFolder:
 			rr.fallbackStaticDir = string(value)
-			rr.fallbackFileServer = http.FileServer(http.Dir(value))
+			rr.fallbackFileServer = newPrecompressedFileServer(string(value))
 		default:
 			log.Println("unknown ReactApp option with type " + reflect.TypeOf(item).String())
 		}
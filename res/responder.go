@@ -169,6 +169,9 @@ func WrapHTTP(server http.Handler) Responder {
 // the client receives only a generic message — unless er.ReturnErrorMessageToClient
 // is set (typically dev mode), in which case the detail is passed through. This
 // prevents raw DB/driver/internal error text from leaking to callers.
+//
+// Errors that implement er.ErrorWithHttpStatus (e.g. model.ErrStaleObject -> 409) are
+// reported with that status instead of 500, and er.ClientSafeErr messages are always passed through.
 func Error(err error) Responder {
 	if err == nil {
 		return AppError(er.GenericErrorMessage)
@@ -176,11 +179,19 @@ func Error(err error) Responder {
 
 	er.ErrorLog.Println(err.Error())
 
-	if er.ReturnErrorMessageToClient {
-		return AppError(err.Error())
+	status := er.HttpStatus(err, http.StatusInternalServerError)
+
+	if er.ReturnErrorMessageToClient || er.IsClientSafe(err) {
+		return &responder{
+			status: status,
+			data:   errorData(err.Error(), "", "", nil),
+		}
 	}
 
-	return AppError(er.GenericErrorMessage)
+	return &responder{
+		status: status,
+		data:   errorData(er.GenericErrorMessage, "", "", nil),
+	}
 }
 
 type freeformResponder struct {
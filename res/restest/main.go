@@ -0,0 +1,343 @@
+// Package restest sends requests to a router (or any http.Handler) in tests, without a network
+//
+//	func TestGetPerson(t *testing.T) {
+//		restest.UseTestJWTKey(t) // before the router is setup (httpauth.Setup)
+//		client := restest.New(t, setupRouter())
+//
+//		client.As(&auth.UserInfo{UserID: 1, Role: RoleAdmin}).
+//			WithAPIVersion("2").
+//			Get("/api/person/1").
+//			ExpectStatus(http.StatusOK).
+//			ExpectJSON("name", "bob")
+//	}
+package restest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ntbosscher/gobase/apiversion"
+	"github.com/ntbosscher/gobase/auth"
+	"github.com/ntbosscher/gobase/auth/httpauth"
+	"github.com/ntbosscher/gobase/res"
+)
+
+// TestJWTKey signs the tokens minted by Client.As when UseTestJWTKey is used
+var TestJWTKey = []byte("gobase-restest-jwt-key-not-for-production-use-0123456789")
+
+// UseTestJWTKey makes httpauth use TestJWTKey instead of reading ./.jwtkey until t ends.
+// Call it before the router is setup.
+func UseTestJWTKey(t testing.TB) {
+	httpauth.SetJWTKey(TestJWTKey)
+	t.Cleanup(func() {
+		httpauth.SetJWTKey(nil)
+	})
+}
+
+// Client builds requests for handler. The With* and As methods return a copy, so a
+// base client can be shared between tests.
+type Client struct {
+	t       testing.TB
+	handler http.Handler
+
+	user    *auth.UserInfo
+	version string
+	ip      string
+	header  http.Header
+}
+
+func New(t testing.TB, handler http.Handler) *Client {
+	return &Client{
+		t:       t,
+		handler: handler,
+		ip:      "192.0.2.1",
+		header:  http.Header{},
+	}
+}
+
+func (c *Client) clone() *Client {
+	clone := *c
+	clone.header = c.header.Clone()
+	return &clone
+}
+
+// As authenticates requests as user with a real access token (see httpauth.CreateAccessToken).
+// Use nil for anonymous requests.
+func (c *Client) As(user *auth.UserInfo) *Client {
+	clone := c.clone()
+	clone.user = user
+	return clone
+}
+
+// WithAPIVersion sets the X-APIVersion header (see apiversion)
+func (c *Client) WithAPIVersion(version string) *Client {
+	clone := c.clone()
+	clone.version = version
+	return clone
+}
+
+// WithIP sets the request's remote address, which requestip.Middleware(requestip.NoProxies()) reports
+// as the client IP. The default is 192.0.2.1.
+func (c *Client) WithIP(ip string) *Client {
+	clone := c.clone()
+	clone.ip = ip
+	return clone
+}
+
+func (c *Client) WithHeader(key string, value string) *Client {
+	clone := c.clone()
+	clone.header.Set(key, value)
+	return clone
+}
+
+func (c *Client) Get(path string) *Response {
+	return c.Do("GET", path, nil)
+}
+
+func (c *Client) Delete(path string) *Response {
+	return c.Do("DELETE", path, nil)
+}
+
+// Post sends body as json
+func (c *Client) Post(path string, body interface{}) *Response {
+	return c.Do("POST", path, body)
+}
+
+// Put sends body as json
+func (c *Client) Put(path string, body interface{}) *Response {
+	return c.Do("PUT", path, body)
+}
+
+// Do sends a request. body is sent as-is if it's an io.Reader, []byte or string, otherwise it's json encoded.
+func (c *Client) Do(method string, path string, body interface{}) *Response {
+	c.t.Helper()
+
+	rq := httptest.NewRequest(method, path, c.body(body))
+
+	for key, values := range c.header {
+		rq.Header[key] = values
+	}
+
+	if body != nil && rq.Header.Get("Content-Type") == "" {
+		rq.Header.Set("Content-Type", "application/json")
+	}
+
+	if c.version != "" {
+		rq.Header.Set(apiversion.VersionHeaderName, c.version)
+	}
+
+	if c.ip != "" {
+		rq.RemoteAddr = c.ip + ":1234"
+		if strings.Contains(c.ip, ":") {
+			rq.RemoteAddr = "[" + c.ip + "]:1234"
+		}
+	}
+
+	if c.user != nil {
+		token, err := httpauth.CreateAccessToken(c.user, 0)
+		if err != nil {
+			c.t.Fatal("restest: failed to create access token:", err)
+		}
+
+		rq.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	c.handler.ServeHTTP(w, rq)
+
+	return &Response{
+		t:       c.t,
+		request: method + " " + path,
+		Status:  w.Code,
+		Header:  w.Header(),
+		Body:    w.Body.Bytes(),
+	}
+}
+
+func (c *Client) body(body interface{}) io.Reader {
+	switch value := body.(type) {
+	case nil:
+		return nil
+	case io.Reader:
+		return value
+	case []byte:
+		return bytes.NewReader(value)
+	case string:
+		return strings.NewReader(value)
+	default:
+		js, err := res.GetJSONInstance().Marshal(value)
+		if err != nil {
+			c.t.Fatal("restest: failed to encode body:", err)
+		}
+
+		return bytes.NewReader(js)
+	}
+}
+
+type Response struct {
+	t       testing.TB
+	request string
+
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// ErrorPayload is the body res sends for errors (er.Check, er.Throw, res.BadRequest...)
+type ErrorPayload struct {
+	Error         string          `json:"error"`
+	Message       string          `json:"message"`
+	StackTrace    string          `json:"stackTrace"`
+	Details       json.RawMessage `json:"details"`
+	CorrelationID string          `json:"correlationId"`
+}
+
+// Decode json decodes the body into dest (with the same json settings as res)
+func (r *Response) Decode(dest interface{}) *Response {
+	r.t.Helper()
+
+	if err := res.GetJSONInstance().Unmarshal(r.Body, dest); err != nil {
+		r.t.Fatalf("%s: failed to decode response: %v\n%s", r.request, err, r.Body)
+	}
+
+	return r
+}
+
+// Error decodes an error response
+func (r *Response) Error() *ErrorPayload {
+	r.t.Helper()
+
+	payload := &ErrorPayload{}
+	if err := json.Unmarshal(r.Body, payload); err != nil {
+		r.t.Fatalf("%s: response isn't an error payload: %v\n%s", r.request, err, r.Body)
+	}
+
+	return payload
+}
+
+func (r *Response) ExpectStatus(status int) *Response {
+	r.t.Helper()
+
+	if r.Status != status {
+		r.t.Fatalf("%s: expected status %d, got %d\n%s", r.request, status, r.Status, r.Body)
+	}
+
+	return r
+}
+
+func (r *Response) ExpectHeader(key string, value string) *Response {
+	r.t.Helper()
+
+	if actual := r.Header.Get(key); actual != value {
+		r.t.Fatalf("%s: expected header %s to be '%s', got '%s'", r.request, key, value, actual)
+	}
+
+	return r
+}
+
+// ExpectError checks that the response is an error whose message contains text
+func (r *Response) ExpectError(text string) *Response {
+	r.t.Helper()
+
+	if r.Status < 400 {
+		r.t.Fatalf("%s: expected an error, got status %d\n%s", r.request, r.Status, r.Body)
+	}
+
+	payload := r.Error()
+	if !strings.Contains(payload.Error, text) && !strings.Contains(payload.Message, text) {
+		r.t.Fatalf("%s: expected error containing '%s', got '%s'", r.request, text, payload.Error)
+	}
+
+	return r
+}
+
+// JSON returns the value at path in the json body. Path is a dot separated list of keys
+// and array indexes, e.g. "items.0.name" or "items[0].name". "" is the whole body.
+func (r *Response) JSON(path string) interface{} {
+	r.t.Helper()
+
+	var value interface{}
+	if err := json.Unmarshal(r.Body, &value); err != nil {
+		r.t.Fatalf("%s: response isn't json: %v\n%s", r.request, err, r.Body)
+	}
+
+	value, err := lookup(value, path)
+	if err != nil {
+		r.t.Fatalf("%s: %v\n%s", r.request, err, r.Body)
+	}
+
+	return value
+}
+
+// ExpectJSON checks the value at path (see JSON). expected is compared by its json encoding,
+// so ExpectJSON("id", 1) and ExpectJSON("user", User{...}) work as expected.
+func (r *Response) ExpectJSON(path string, expected interface{}) *Response {
+	r.t.Helper()
+
+	actual := r.JSON(path)
+
+	js, err := res.GetJSONInstance().Marshal(expected)
+	if err != nil {
+		r.t.Fatal("restest: failed to encode expected value:", err)
+	}
+
+	var normalized interface{}
+	if err := json.Unmarshal(js, &normalized); err != nil {
+		r.t.Fatal("restest: failed to decode expected value:", err)
+	}
+
+	if !reflect.DeepEqual(actual, normalized) {
+		actualJS, _ := json.Marshal(actual)
+		r.t.Fatalf("%s: expected %s to be %s, got %s", r.request, describePath(path), js, actualJS)
+	}
+
+	return r
+}
+
+func describePath(path string) string {
+	if path == "" {
+		return "body"
+	}
+
+	return "'" + path + "'"
+}
+
+func lookup(value interface{}, path string) (interface{}, error) {
+	path = strings.ReplaceAll(path, "[", ".")
+	path = strings.ReplaceAll(path, "]", "")
+
+	if path == "" {
+		return value, nil
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		switch current := value.(type) {
+		case map[string]interface{}:
+			next, ok := current[key]
+			if !ok {
+				return nil, fmt.Errorf("json path %s: key '%s' not found", path, key)
+			}
+
+			value = next
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(current) {
+				return nil, fmt.Errorf("json path %s: index '%s' out of range", path, key)
+			}
+
+			value = current[index]
+		default:
+			return nil, fmt.Errorf("json path %s: can't read '%s' of a %T", path, key, value)
+		}
+	}
+
+	return value, nil
+}
@@ -0,0 +1,81 @@
+package restest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/ntbosscher/gobase/apiversion"
+	"github.com/ntbosscher/gobase/auth"
+	"github.com/ntbosscher/gobase/auth/httpauth"
+	"github.com/ntbosscher/gobase/er"
+	"github.com/ntbosscher/gobase/requestip"
+	"github.com/ntbosscher/gobase/res"
+)
+
+const roleAdmin auth.TRole = 1
+
+func setupRouter(t *testing.T) http.Handler {
+	UseTestJWTKey(t)
+
+	rt := res.NewRouter()
+	rt.Use(apiversion.Middleware(), requestip.Middleware(requestip.NoProxies()))
+
+	authRouter := httpauth.Setup(rt, httpauth.Config{
+		CredentialChecker: func(ctx context.Context, credential *httpauth.Credential) (*auth.UserInfo, error) {
+			return nil, nil
+		},
+	})
+
+	authRouter.Get("/api/me", roleAdmin, func(rq *res.Request) res.Responder {
+		return res.Ok(map[string]interface{}{
+			"userId":  auth.User(rq.Context()),
+			"version": rq.APIVersion().String(),
+			"ip":      requestip.IP(rq.Context()),
+		})
+	})
+
+	authRouter.Post("/api/echo", auth.Public, func(rq *res.Request) res.Responder {
+		var input struct {
+			Names []string `validate:"required"`
+		}
+
+		rq.MustParseJSON(&input)
+		if input.Names[0] == "fail" {
+			er.ThrowClientSafe("can't be fail")
+		}
+
+		return res.Ok(input)
+	})
+
+	return rt
+}
+
+func TestClient(t *testing.T) {
+	client := New(t, setupRouter(t))
+
+	client.Get("/api/me").ExpectStatus(http.StatusUnauthorized)
+
+	client.As(&auth.UserInfo{UserID: 7, Role: roleAdmin}).
+		WithAPIVersion("2.1").
+		WithIP("203.0.113.9").
+		Get("/api/me").
+		ExpectStatus(http.StatusOK).
+		ExpectHeader("Content-Type", "application/json").
+		ExpectJSON("userId", 7).
+		ExpectJSON("version", "2.1").
+		ExpectJSON("ip", "203.0.113.9")
+
+	client.Post("/api/echo", map[string]interface{}{"names": []string{"a", "b"}}).
+		ExpectStatus(http.StatusOK).
+		ExpectJSON("names[1]", "b").
+		ExpectJSON("", map[string]interface{}{"names": []string{"a", "b"}})
+
+	client.Post("/api/echo", map[string]interface{}{"names": []string{"fail"}}).
+		ExpectError("can't be fail")
+
+	payload := client.Post("/api/echo", "{}").ExpectStatus(http.StatusBadRequest).Error()
+	if string(payload.Details) != `[{"field":"names","rule":"required","message":"is required"}]` {
+		t.Fatal("unexpected details", string(payload.Details))
+	}
+}
@@ -97,8 +97,10 @@ func (rt *Router) Use(mwf ...mux.MiddlewareFunc) {
 	rt.next.Use(mwf...)
 }
 
+// StaticFileDir serves the files in srcDir. When the client supports it, precompressed
+// siblings (foo.js.br, foo.js.gz) are served in place of the original file.
 func (rt *Router) StaticFileDir(urlPrefix string, srcDir string) {
-	fileServer := http.StripPrefix(urlPrefix, http.FileServer(http.Dir(srcDir)))
+	fileServer := http.StripPrefix(urlPrefix, newPrecompressedFileServer(srcDir))
 	rt.next.PathPrefix(urlPrefix).Handler(fileServer)
 }
 
@@ -148,6 +150,8 @@ func WrapHTTPFunc(handler HandlerFunc2) http.HandlerFunc {
 			req.Body = http.MaxBytesReader(wr, req.Body, MaxRequestBodySize)
 		}
 
+		captureAccessLogRequest(req)
+
 		res := handler(NewRequest(wr, req))
 		res.Respond(wr, req)
 	}
@@ -314,21 +318,34 @@ func (r *Request) GetCookieValue(name string) string {
 	return c.Value
 }
 
+// ParseJSON decodes the request body into result and checks its validate tags (see Validate).
+// Validation failures are returned as ValidationErrors.
 func (r *Request) ParseJSON(result interface{}) error {
-	return json.NewDecoder(r.req.Body).Decode(result)
+	if err := json.NewDecoder(r.req.Body).Decode(result); err != nil {
+		return err
+	}
+
+	return Validate(result)
 }
 
+// MustParseJSON is ParseJSON that responds with a 400 when the body can't be decoded or is invalid
 func (r *Request) MustParseJSON(result interface{}) {
-	err := r.ParseJSON(result)
-	er.CheckForDecode(err)
+	checkParseError(r.ParseJSON(result))
 }
 
 func MustParseJSON[T any](r *Request, result T) T {
-	err := r.ParseJSON(result)
-	er.CheckForDecode(err)
+	checkParseError(r.ParseJSON(result))
 	return result
 }
 
+func checkParseError(err error) {
+	if _, ok := err.(ValidationErrors); ok {
+		er.Check(err)
+	}
+
+	er.CheckForDecode(err)
+}
+
 func (r *Request) APIVersion() *apiversion.Ver {
 	return apiversion.Current(r.Context())
 }
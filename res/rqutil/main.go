@@ -340,7 +340,7 @@ func GetListQrOpt[T any](rq *res.Request, qr squirrel.SelectBuilder, params *Get
 	}
 
 	count := 0
-	squtil.MustGetContext(rq.Context(), &count, model.Builder.Select("count(*)").FromSelect(qr, "d"))
+	squtil.MustGetContext(rq.Context(), &count, model.Builder.Select("count(*)").FromSelect(model.ScopeSelect(rq.Context(), qr), "d"))
 
 	return res.Ok(map[string]interface{}{
 		"data":  list,
@@ -0,0 +1,390 @@
+package res
+
+import (
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/ntbosscher/gobase/er"
+)
+
+// ValidationTag is the struct tag read by Validate
+//
+//	type CreatePersonInput struct {
+//		Email     string    `validate:"required,email"`
+//		FirstName string    `validate:"required,max=50"`
+//		Age       int       `validate:"min=0,max=150"`
+//		Role      string    `validate:"oneof=admin user"`
+//		Tags      []string  `validate:"max=10,dive,min=1,max=20"`
+//		Phone     string    `validate:"regexp=^[0-9 +()-]+$"`
+//		Addresses []Address `validate:"required"` // structs (and slices of them) are validated recursively
+//	}
+//
+// Rules:
+//   - required: not empty (zero value, nil, empty string/slice/map, invalid nulls.*)
+//   - min=n, max=n, len=n: the length of strings (in characters), slices and maps, or the value of numbers
+//   - oneof=a b c: one of the space separated values
+//   - email, url: a valid email address or absolute url
+//   - regexp=pattern: matches the pattern. It has to be the last rule since the pattern may contain commas.
+//   - dive: the rules after dive apply to each element of the slice/map
+//
+// Rules other than required are skipped for empty strings and null values, add required to disallow them.
+const ValidationTag = "validate"
+
+// FieldError is a single validation failure. Field is the json path (e.g. "addresses[0].street")
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors is returned by Validate. When it reaches er.Check (e.g. through MustParseJSON or
+// MustValidate) the client gets a 400 with the field errors in "details".
+type ValidationErrors []*FieldError
+
+func (v ValidationErrors) Error() string {
+	parts := []string{}
+	for _, item := range v {
+		parts = append(parts, item.Field+" "+item.Message)
+	}
+
+	return "validation failed: " + strings.Join(parts, ", ")
+}
+
+func (v ValidationErrors) HttpStatus() int {
+	return http.StatusBadRequest
+}
+
+func (v ValidationErrors) IsClientSafeErr() bool {
+	return true
+}
+
+func (v ValidationErrors) GetClientDetails() any {
+	return []*FieldError(v)
+}
+
+// Validate checks value (a struct or pointer to a struct) against its validate tags.
+// It returns nil when everything passes.
+func Validate(value interface{}) error {
+	v := &validator{}
+	v.value("", reflect.ValueOf(value), nil)
+
+	if len(v.errors) == 0 {
+		return nil
+	}
+
+	return v.errors
+}
+
+// MustValidate is Validate that throws a 400 (with field details) on failure
+func MustValidate(value interface{}) {
+	er.Check(Validate(value))
+}
+
+type validator struct {
+	errors ValidationErrors
+}
+
+type validationRule struct {
+	name  string
+	param string
+}
+
+func (v *validator) fail(path string, rule string, message string) {
+	v.errors = append(v.errors, &FieldError{Field: path, Rule: rule, Message: message})
+}
+
+// value validates a value with its rules and recurses into structs/slices/maps
+func (v *validator) value(path string, value reflect.Value, rules []validationRule) {
+	value, isNull, indirect := unwrapNullable(value)
+
+	var elementRules []validationRule
+
+	for i, rule := range rules {
+		if rule.name == "dive" {
+			elementRules = rules[i+1:]
+			rules = rules[:i]
+			break
+		}
+	}
+
+	for _, rule := range rules {
+		if rule.name == "required" {
+			// a pointer/nulls value that's set counts as present, even if it's 0 or false
+			if isNull || (!indirect && value.IsZero()) || (hasLength(value) && value.Len() == 0) {
+				v.fail(path, rule.name, "is required")
+				return
+			}
+
+			continue
+		}
+
+		if isNull || (value.Kind() == reflect.String && value.Len() == 0) {
+			continue
+		}
+
+		if message := checkRule(value, rule); message != "" {
+			v.fail(path, rule.name, message)
+		}
+	}
+
+	if isNull {
+		return
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		v.structFields(path, value)
+	case reflect.Slice, reflect.Array:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			return
+		}
+
+		for i := 0; i < value.Len(); i++ {
+			v.value(path+"["+strconv.Itoa(i)+"]", value.Index(i), elementRules)
+		}
+	case reflect.Map:
+		iter := value.MapRange()
+		for iter.Next() {
+			v.value(path+"["+fmt.Sprint(iter.Key().Interface())+"]", iter.Value(), elementRules)
+		}
+	}
+}
+
+func (v *validator) structFields(path string, value reflect.Value) {
+	t := value.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get(ValidationTag)
+
+		if f.Anonymous && f.Tag.Get("json") == "" {
+			// embedded struct fields are part of the parent in json
+			v.value(path, value.Field(i), parseValidationTag(tag))
+			continue
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		name, ok := jsonFieldName(f)
+		if !ok {
+			continue
+		}
+
+		if path != "" {
+			name = path + "." + name
+		}
+
+		v.value(name, value.Field(i), parseValidationTag(tag))
+	}
+}
+
+// jsonFieldName matches the naming used by the jsoniter instance in this package
+func jsonFieldName(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = JsonRenameKeysToCamelCase(f.Name)
+	}
+
+	return name, true
+}
+
+// unwrapNullable dereferences pointers/interfaces and nullable structs (nulls.String, model.NullDate...).
+// indirect is true when value was behind one of those.
+func unwrapNullable(value reflect.Value) (unwrapped reflect.Value, isNull bool, indirect bool) {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return value, true, true
+		}
+
+		value = value.Elem()
+		indirect = true
+	}
+
+	if !value.IsValid() {
+		return value, true, true
+	}
+
+	t := value.Type()
+	if t.Kind() != reflect.Struct || t.NumField() != 2 {
+		return value, false, indirect
+	}
+
+	valid, ok := t.FieldByName("Valid")
+	if !ok || valid.Type.Kind() != reflect.Bool {
+		return value, false, indirect
+	}
+
+	if !value.FieldByIndex(valid.Index).Bool() {
+		return value, true, true
+	}
+
+	return value.Field(1 - valid.Index[0]), false, true
+}
+
+func hasLength(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return true
+	default:
+		return false
+	}
+}
+
+var validationTagCache sync.Map
+
+func parseValidationTag(tag string) []validationRule {
+	if tag == "" {
+		return nil
+	}
+
+	if cached, ok := validationTagCache.Load(tag); ok {
+		return cached.([]validationRule)
+	}
+
+	var rules []validationRule
+	rest := tag
+
+	for rest != "" {
+		var part string
+
+		if strings.HasPrefix(rest, "regexp=") {
+			part, rest = rest, ""
+		} else {
+			part, rest, _ = strings.Cut(rest, ",")
+		}
+
+		name, param, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if name == "" {
+			continue
+		}
+
+		rule := validationRule{name: name, param: param}
+
+		switch name {
+		case "required", "email", "url", "dive", "oneof":
+		case "min", "max", "len":
+			if _, err := strconv.ParseFloat(param, 64); err != nil {
+				panic("gobase/res: invalid validate tag '" + tag + "': " + name + " needs a number")
+			}
+		case "regexp":
+			compileValidationRegexp(param)
+		default:
+			panic("gobase/res: invalid validate tag '" + tag + "': unknown rule '" + name + "'")
+		}
+
+		rules = append(rules, rule)
+	}
+
+	validationTagCache.Store(tag, rules)
+	return rules
+}
+
+var validationRegexps sync.Map
+
+func compileValidationRegexp(pattern string) *regexp.Regexp {
+	if cached, ok := validationRegexps.Load(pattern); ok {
+		return cached.(*regexp.Regexp)
+	}
+
+	re := regexp.MustCompile(pattern)
+	validationRegexps.Store(pattern, re)
+	return re
+}
+
+// checkRule returns the failure message, or "" if value passes
+func checkRule(value reflect.Value, rule validationRule) string {
+	switch rule.name {
+	case "min", "max", "len":
+		return checkSize(value, rule)
+	case "oneof":
+		str := fmt.Sprint(value.Interface())
+		options := strings.Fields(rule.param)
+
+		for _, option := range options {
+			if str == option {
+				return ""
+			}
+		}
+
+		return "must be one of: " + strings.Join(options, ", ")
+	case "email":
+		str := fmt.Sprint(value.Interface())
+		addr, err := mail.ParseAddress(str)
+		if err != nil || addr.Address != str {
+			return "must be a valid email address"
+		}
+	case "url":
+		u, err := url.Parse(fmt.Sprint(value.Interface()))
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return "must be a valid url"
+		}
+	case "regexp":
+		if !compileValidationRegexp(rule.param).MatchString(fmt.Sprint(value.Interface())) {
+			return "has an invalid format"
+		}
+	}
+
+	return ""
+}
+
+func checkSize(value reflect.Value, rule validationRule) string {
+	limit, _ := strconv.ParseFloat(rule.param, 64)
+
+	var actual float64
+	unit := ""
+
+	switch value.Kind() {
+	case reflect.String:
+		actual = float64(utf8.RuneCountInString(value.String()))
+		unit = " characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		actual = float64(value.Len())
+		unit = " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		actual = float64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		actual = float64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		actual = value.Float()
+	default:
+		return ""
+	}
+
+	switch {
+	case rule.name == "min" && actual < limit:
+		if unit == "" {
+			return "must be at least " + rule.param
+		}
+
+		return "must have at least " + rule.param + unit
+	case rule.name == "max" && actual > limit:
+		if unit == "" {
+			return "must be at most " + rule.param
+		}
+
+		return "must have at most " + rule.param + unit
+	case rule.name == "len" && actual != limit:
+		if unit == "" {
+			return "must be " + rule.param
+		}
+
+		return "must have " + rule.param + unit
+	}
+
+	return ""
+}
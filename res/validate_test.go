@@ -0,0 +1,109 @@
+package res
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gobuffalo/nulls"
+)
+
+type validateAddress struct {
+	Street string `validate:"required"`
+}
+
+type validateInput struct {
+	Email     string            `validate:"required,email"`
+	Name      string            `json:"fullName" validate:"required,max=5"`
+	Age       int               `validate:"min=18,max=150"`
+	Role      string            `validate:"oneof=admin user"`
+	Website   string            `validate:"url"`
+	Phone     nulls.String      `validate:"regexp=^[0-9]+$"`
+	Count     *int              `validate:"required"`
+	Tags      []string          `validate:"max=2,dive,min=2"`
+	Addresses []validateAddress `validate:"required"`
+}
+
+func TestValidate(t *testing.T) {
+	zero := 0
+
+	valid := &validateInput{
+		Email:     "a@example.com",
+		Name:      "Bob",
+		Age:       20,
+		Role:      "user",
+		Count:     &zero,
+		Tags:      []string{"ab"},
+		Addresses: []validateAddress{{Street: "Main"}},
+	}
+
+	if err := Validate(valid); err != nil {
+		t.Fatal("expected valid input", err)
+	}
+
+	invalid := &validateInput{
+		Email:     "not-an-email",
+		Name:      "Robert",
+		Age:       12,
+		Role:      "owner",
+		Website:   "example.com",
+		Phone:     nulls.NewString("12a"),
+		Tags:      []string{"ab", "c"},
+		Addresses: []validateAddress{{}},
+	}
+
+	err := Validate(invalid)
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+
+	got := map[string]string{}
+	for _, item := range err.(ValidationErrors) {
+		got[item.Field] = item.Rule
+	}
+
+	expected := map[string]string{
+		"email":               "email",
+		"fullName":            "max",
+		"age":                 "min",
+		"role":                "oneof",
+		"website":             "url",
+		"phone":               "regexp",
+		"count":               "required",
+		"tags[1]":             "min",
+		"addresses[0].street": "required",
+	}
+
+	for field, rule := range expected {
+		if got[field] != rule {
+			t.Error("expected", field, "to fail", rule, "got", got)
+		}
+	}
+
+	if len(got) != len(expected) {
+		t.Error("unexpected errors", got)
+	}
+}
+
+func TestMustParseJSONValidation(t *testing.T) {
+	var input struct {
+		Email string `validate:"required,email"`
+	}
+
+	handler := WrapHTTPFunc(func(rq *Request) Responder {
+		rq.MustParseJSON(&input)
+		return Ok()
+	})
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("POST", "/", strings.NewReader(`{"email": "x"}`)))
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatal("expected 400, got", w.Code)
+	}
+
+	if !strings.Contains(w.Body.String(), `"field":"email"`) {
+		t.Fatal("expected field details, got", w.Body.String())
+	}
+}
@@ -231,32 +231,36 @@ var openWebSocketCount int64
 
 // acquireWebSocketSlot reserves a connection slot, returning false if the process
 // is already at MaxConcurrentWebSockets. Every successful reservation must be
-// released with releaseWebSocketSlot when the connection closes. The counter is
-// always maintained (even when the cap is disabled) so toggling
-// MaxConcurrentWebSockets at runtime can't underflow it.
+// released with releaseWebSocketSlot when the connection closes.
 func acquireWebSocketSlot() bool {
-	limit := MaxConcurrentWebSockets
+	return acquireConnectionSlot(&openWebSocketCount, MaxConcurrentWebSockets)
+}
+
+func releaseWebSocketSlot() {
+	atomic.AddInt64(&openWebSocketCount, -1)
+}
+
+// acquireConnectionSlot increments count unless it's already at limit (0 disables the limit).
+// The counter is always maintained (even when the cap is disabled) so toggling
+// the limit at runtime can't underflow it.
+func acquireConnectionSlot(count *int64, limit int64) bool {
 	if limit <= 0 {
-		atomic.AddInt64(&openWebSocketCount, 1)
+		atomic.AddInt64(count, 1)
 		return true
 	}
 
 	for {
-		cur := atomic.LoadInt64(&openWebSocketCount)
+		cur := atomic.LoadInt64(count)
 		if cur >= limit {
 			return false
 		}
 
-		if atomic.CompareAndSwapInt64(&openWebSocketCount, cur, cur+1) {
+		if atomic.CompareAndSwapInt64(count, cur, cur+1) {
 			return true
 		}
 	}
 }
 
-func releaseWebSocketSlot() {
-	atomic.AddInt64(&openWebSocketCount, -1)
-}
-
 var websocketIdCounter = 0
 var muWebsocketIdCounter = &sync.Mutex{}
 